@@ -5,6 +5,8 @@ go 1.19
 require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.7.0
+	golang.org/x/time v0.3.0
 )
 
 require (
@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+)
+
+var ErrDraining = errors.New("service is shutting down")
+
+// CheckFunc reports the health of a dependency. It must return once ctx is
+// done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report is the outcome of every registered check.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Health runs the readiness checks of a service. Liveness only depends on the
+// process being able to answer, so it has no checks.
+type Health struct {
+	mu       sync.RWMutex
+	checks   []check
+	draining atomic.Bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Register adds a readiness check. Each run of fn is bounded by timeout.
+func (h *Health) Register(name string, timeout time.Duration, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// Drain makes every later readiness report fail, so that load balancers stop
+// routing traffic while in-flight requests finish.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Ready runs every check concurrently and reports the service ready if all
+// of them pass and the service is not draining.
+func (h *Health) Ready(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := make([]check, len(h.checks))
+	copy(checks, h.checks)
+	h.mu.RUnlock()
+
+	report := Report{Status: StatusPass, Checks: make(map[string]Result, len(checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+
+			result := run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusPass {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+
+	wg.Wait()
+
+	if h.Draining() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = Result{Status: StatusFail, Latency: "0s", Error: ErrDraining.Error()}
+	}
+
+	return report
+}
+
+func run(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	latency := time.Since(start)
+
+	if err == nil {
+		return Result{Status: StatusPass, Latency: latency.String()}
+	}
+	return Result{Status: StatusFail, Latency: latency.String(), Error: err.Error()}
+}
@@ -0,0 +1,66 @@
+package jsonlog
+
+import (
+	"context"
+	"sync"
+)
+
+// Well-known correlation keys set by the transport layers.
+const (
+	KeyRequestID = "request_id"
+	KeyTraceID   = "trace_id"
+	KeyUserID    = "user_id"
+	KeyRoute     = "route"
+)
+
+type contextKey struct{}
+
+// fieldSet is mutable so that fields added deep in a call chain, such as the
+// user resolved by an authentication middleware, are also seen by loggers
+// holding an outer context, such as an access log.
+type fieldSet struct {
+	mu     sync.RWMutex
+	fields []Field
+}
+
+// NewContext returns a copy of parent carrying its own set of log fields,
+// starting with those of parent, if any, followed by fields.
+func NewContext(parent context.Context, fields ...Field) context.Context {
+	set := &fieldSet{}
+	set.fields = append(set.fields, FieldsFromContext(parent)...)
+	set.fields = append(set.fields, fields...)
+	return context.WithValue(parent, contextKey{}, set)
+}
+
+// AddFields adds fields to the set carried by ctx. It does nothing if ctx was
+// not created by NewContext.
+func AddFields(ctx context.Context, fields ...Field) {
+	set, ok := ctx.Value(contextKey{}).(*fieldSet)
+	if !ok {
+		return
+	}
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.fields = append(set.fields, fields...)
+}
+
+func FieldsFromContext(ctx context.Context) []Field {
+	set, ok := ctx.Value(contextKey{}).(*fieldSet)
+	if !ok {
+		return nil
+	}
+
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	return append([]Field(nil), set.fields...)
+}
+
+// Ctx returns a child logger bound to the fields carried by ctx.
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
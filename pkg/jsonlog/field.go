@@ -0,0 +1,51 @@
+package jsonlog
+
+import "time"
+
+// Field is a typed key/value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value any
+}
+
+func String(key string, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration is rendered in Go duration notation, e.g. "1.5ms".
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value.String()}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value.UTC().Format(time.RFC3339Nano)}
+}
+
+// Err stores the message of err under the "error" key.
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error", Value: nil}
+	}
+	return Field{Key: "error", Value: err.Error()}
+}
+
+// Any stores value as encoding/json renders it.
+func Any(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
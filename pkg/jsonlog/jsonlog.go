@@ -2,18 +2,23 @@ package jsonlog
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Level int8
 
 const (
-	LevelInfo Level = iota
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
 	LevelError
 	LevelFatal
 	LevelOff
@@ -21,38 +26,135 @@ const (
 
 func (l Level) String() string {
 	switch l {
+	case LevelDebug:
+		return "DEBUG"
 	case LevelInfo:
 		return "INFO"
+	case LevelWarn:
+		return "WARN"
 	case LevelError:
 		return "ERROR"
 	case LevelFatal:
 		return "FATAL"
+	case LevelOff:
+		return "OFF"
 	default:
 		return ""
 	}
 }
 
+// ParseLevel is the inverse of Level.String and ignores case.
+func ParseLevel(s string) (Level, error) {
+	for l := LevelDebug; l <= LevelOff; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return LevelOff, fmt.Errorf("unknown log level %q", s)
+}
+
+// Option configures a Logger created by New.
+type Option func(c *core)
+
+// WithStackTraces adds a stack trace to Error and Fatal entries.
+func WithStackTraces() Option {
+	return func(c *core) {
+		c.stackTraces = true
+	}
+}
+
+// WithSampling caps the number of identical Debug, Info and Warn entries:
+// within each tick the first entries with a given level and message are
+// written, then only every thereafter-th one. Errors are never sampled.
+func WithSampling(tick time.Duration, first int, thereafter int) Option {
+	return func(c *core) {
+		c.sampler = &sampler{
+			tick:       tick,
+			first:      first,
+			thereafter: thereafter,
+			counts:     make(map[samplerKey]int),
+		}
+	}
+}
+
+// core is shared by a logger and every child created with With, so that the
+// output, the lock and the level are common to all of them.
+type core struct {
+	out         io.Writer
+	mu          sync.Mutex
+	level       atomic.Int32
+	stackTraces bool
+	sampler     *sampler
+}
+
 type Logger struct {
-	out      io.Writer
-	minLevel Level
-	mu       sync.Mutex
+	core   *core
+	fields []Field
 }
 
-func New(out io.Writer, minLevel Level) *Logger {
-	return &Logger{
-		out:      out,
-		minLevel: minLevel,
+func New(out io.Writer, minLevel Level, opts ...Option) *Logger {
+	c := &core{out: out}
+	c.level.Store(int32(minLevel))
+	for _, opt := range opts {
+		opt(c)
 	}
+	return &Logger{core: c}
+}
+
+// With returns a child logger adding fields to every entry. Fields passed to
+// a single call take precedence over bound ones with the same key.
+func (l *Logger) With(fields ...Field) *Logger {
+	bound := make([]Field, 0, len(l.fields)+len(fields))
+	bound = append(bound, l.fields...)
+	bound = append(bound, fields...)
+	return &Logger{core: l.core, fields: bound}
+}
+
+// SetLevel changes the minimum level of the logger and all of its children
+// at runtime.
+func (l *Logger) SetLevel(level Level) {
+	l.core.level.Store(int32(level))
+}
+
+func (l *Logger) Level() Level {
+	return Level(l.core.level.Load())
+}
+
+// Enabled reports whether entries of the given level are written, to skip
+// building expensive fields.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+func (l *Logger) Debug(message string, fields ...Field) {
+	l.print(LevelDebug, message, fields)
+}
+
+func (l *Logger) Info(message string, fields ...Field) {
+	l.print(LevelInfo, message, fields)
+}
+
+func (l *Logger) Warn(message string, fields ...Field) {
+	l.print(LevelWarn, message, fields)
+}
+
+func (l *Logger) Error(message string, fields ...Field) {
+	l.print(LevelError, message, fields)
+}
+
+func (l *Logger) Fatal(message string, fields ...Field) {
+	l.print(LevelFatal, message, fields)
+	os.Exit(1)
 }
 
 func (l *Logger) PrintInfo(message string, properties map[string]string) {
-	l.print(LevelInfo, message, properties)
+	l.print(LevelInfo, message, stringFields(properties))
 }
 func (l *Logger) PrintError(err error, properties map[string]string) {
-	l.print(LevelError, err.Error(), properties)
+	l.print(LevelError, err.Error(), stringFields(properties))
 }
 func (l *Logger) PrintFatal(err error, properties map[string]string) {
-	l.print(LevelFatal, err.Error(), properties)
+	l.print(LevelFatal, err.Error(), stringFields(properties))
 	os.Exit(1)
 }
 
@@ -63,39 +165,97 @@ func (l *Logger) PrintErrorRequest(r *http.Request, err error) {
 	})
 }
 
-func (l *Logger) print(level Level, message string, properties map[string]string) (int, error) {
-	if level < l.minLevel {
+func (l *Logger) print(level Level, message string, fields []Field) (int, error) {
+	if !l.Enabled(level) {
 		return 0, nil
 	}
+	if level < LevelError && l.core.sampler != nil && !l.core.sampler.allow(level, message) {
+		return 0, nil
+	}
+
 	aux := struct {
-		Level      string            `json:"level"`
-		Time       string            `json:"time"`
-		Message    string            `json:"message"`
-		Properties map[string]string `json:"properties,omitempty"`
-		Trace      string            `json:"trace,omitempty"`
+		Level      string         `json:"level"`
+		Time       string         `json:"time"`
+		Message    string         `json:"message"`
+		Properties map[string]any `json:"properties,omitempty"`
+		Trace      string         `json:"trace,omitempty"`
 	}{
-		Level:      level.String(),
-		Time:       time.Now().UTC().Format(time.RFC3339),
-		Message:    message,
-		Properties: properties,
+		Level:   level.String(),
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Message: message,
 	}
 
-	if level >= LevelError {
-		aux.Trace = string(debug.Stack())
+	if len(l.fields)+len(fields) > 0 {
+		aux.Properties = make(map[string]any, len(l.fields)+len(fields))
+		for _, field := range l.fields {
+			aux.Properties[field.Key] = field.Value
+		}
+		for _, field := range fields {
+			aux.Properties[field.Key] = field.Value
+		}
 	}
 
-	var line []byte
+	if level >= LevelError && l.core.stackTraces {
+		aux.Trace = string(debug.Stack())
+	}
 
-	line, err := json.MarshalIndent(aux, "", "	")
+	line, err := json.Marshal(aux)
 	if err != nil {
 		line = []byte(LevelError.String() + ": unable to marshal log message: " + err.Error())
 	}
-	l.mu.Lock()
-	defer l.mu.Unlock()
 
-	return l.out.Write(append(line, '\n'))
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+
+	return l.core.out.Write(append(line, '\n'))
 }
 
 func (l *Logger) Write(message []byte) (n int, err error) {
-	return l.print(LevelError, string(message), nil)
+	return l.print(LevelError, strings.TrimSpace(string(message)), nil)
+}
+
+func stringFields(properties map[string]string) []Field {
+	if len(properties) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, len(properties))
+	for key, value := range properties {
+		fields = append(fields, String(key, value))
+	}
+	return fields
+}
+
+type samplerKey struct {
+	level   Level
+	message string
+}
+
+type sampler struct {
+	tick       time.Duration
+	first      int
+	thereafter int
+	mu         sync.Mutex
+	resetAt    time.Time
+	counts     map[samplerKey]int
+}
+
+func (s *sampler) allow(level Level, message string) bool {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.resetAt) {
+		s.counts = make(map[samplerKey]int)
+		s.resetAt = now.Add(s.tick)
+	}
+
+	key := samplerKey{level: level, message: message}
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
 }
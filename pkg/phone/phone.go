@@ -0,0 +1,275 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidCharacters  = errors.New("phone: number contains invalid characters")
+	ErrInvalidNumber      = errors.New("phone: number does not fit the numbering plan")
+	ErrUnknownCountryCode = errors.New("phone: unknown country calling code")
+	ErrUnknownRegion      = errors.New("phone: unknown region")
+)
+
+// maxDigits bounds the digits of a number as typed: the 15 digits E.164
+// allows, plus an international or trunk prefix.
+const maxDigits = 19
+
+// separators are the characters allowed between the digits of a number.
+const separators = " \t-.()/"
+
+// Plan is the numbering plan of a region, simplified to what is needed to
+// tell valid numbers from mistyped ones. Numbers are parsed into their
+// national significant number, which excludes the country code and the trunk
+// prefix.
+type Plan struct {
+	// Region is the ISO 3166-1 alpha-2 code of the region, such as "US".
+	Region string
+	// CountryCode is the country calling code, such as "1". Regions may
+	// share a country code; Leading tells their numbers apart.
+	CountryCode string
+	// InternationalPrefix is dialled within the region before an
+	// international number, such as "011" in the US.
+	InternationalPrefix string
+	// TrunkPrefix is dialled within the region before a national number,
+	// such as "0" in the UK.
+	TrunkPrefix string
+	// Lengths lists the lengths a national significant number may have.
+	Lengths []int
+	// Leading lists the digits a national significant number may start
+	// with. An empty list allows any.
+	Leading []string
+	// Formats lays out the numbers of the region. The first format matching
+	// a number is used; numbers matching none are shown as plain digits.
+	Formats []Format
+}
+
+// Format lays out the national significant numbers starting with Leading
+// that have as many digits as the patterns have # placeholders. National is
+// the layout for dialling within the region, trunk prefix included;
+// International the layout following the country code.
+type Format struct {
+	Leading       string
+	National      string
+	International string
+}
+
+// valid reports whether national is a national significant number of plan.
+func (plan *Plan) valid(national string) bool {
+	if !contains(plan.Lengths, len(national)) {
+		return false
+	}
+	if len(plan.Leading) == 0 {
+		return true
+	}
+	for _, leading := range plan.Leading {
+		if strings.HasPrefix(national, leading) {
+			return true
+		}
+	}
+	return false
+}
+
+// Number is a parsed phone number.
+type Number struct {
+	Region      string
+	CountryCode string
+	National    string
+}
+
+// E164 returns the canonical form of number, such as "+14155552671".
+func (number Number) E164() string {
+	return "+" + number.CountryCode + number.National
+}
+
+func (number Number) String() string {
+	return number.E164()
+}
+
+// Parser parses phone numbers against a set of numbering plans. Numbers
+// written without a country code are taken to belong to the default region.
+type Parser struct {
+	defaultRegion string
+	plans         []Plan
+	byRegion      map[string]*Plan
+}
+
+// NewParser returns a parser for the given plans, which must include the
+// default region and cover every region at most once.
+func NewParser(defaultRegion string, plans []Plan) (*Parser, error) {
+	parser := &Parser{
+		defaultRegion: defaultRegion,
+		plans:         append([]Plan{}, plans...),
+		byRegion:      make(map[string]*Plan, len(plans)),
+	}
+
+	for i := range parser.plans {
+		plan := &parser.plans[i]
+		if _, ok := parser.byRegion[plan.Region]; ok {
+			return nil, fmt.Errorf("phone: duplicate plan for region %q", plan.Region)
+		}
+		parser.byRegion[plan.Region] = plan
+	}
+
+	if _, ok := parser.byRegion[defaultRegion]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownRegion, defaultRegion)
+	}
+
+	return parser, nil
+}
+
+// DefaultRegion returns the region of numbers written without a country
+// code.
+func (parser *Parser) DefaultRegion() string {
+	return parser.defaultRegion
+}
+
+// HasRegion reports whether parser has a plan for region.
+func (parser *Parser) HasRegion(region string) bool {
+	_, ok := parser.byRegion[region]
+	return ok
+}
+
+// Parse parses raw as written in the default region, see ParseIn.
+func (parser *Parser) Parse(raw string) (Number, error) {
+	return parser.ParseIn(raw, parser.defaultRegion)
+}
+
+// ParseIn parses raw as written in region. Numbers may be international,
+// starting with + or the international prefix of region, or national, with
+// or without the trunk prefix. Digits may be separated by spaces, dashes,
+// dots, slashes and parentheses.
+func (parser *Parser) ParseIn(raw string, region string) (Number, error) {
+	raw = strings.TrimSpace(raw)
+
+	international := strings.HasPrefix(raw, "+")
+	if international {
+		raw = raw[1:]
+	}
+
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(separators, r):
+		default:
+			return Number{}, ErrInvalidCharacters
+		}
+	}
+
+	number := digits.String()
+	if number == "" || len(number) > maxDigits {
+		return Number{}, ErrInvalidNumber
+	}
+
+	plan, ok := parser.byRegion[region]
+	if !ok {
+		return Number{}, fmt.Errorf("%w %q", ErrUnknownRegion, region)
+	}
+
+	if !international && plan.InternationalPrefix != "" && strings.HasPrefix(number, plan.InternationalPrefix) {
+		number = number[len(plan.InternationalPrefix):]
+		international = true
+	}
+
+	if international {
+		return parser.parseInternational(number)
+	}
+
+	if plan.valid(number) {
+		return Number{Region: plan.Region, CountryCode: plan.CountryCode, National: number}, nil
+	}
+	if plan.TrunkPrefix != "" && strings.HasPrefix(number, plan.TrunkPrefix) && plan.valid(number[len(plan.TrunkPrefix):]) {
+		return Number{Region: plan.Region, CountryCode: plan.CountryCode, National: number[len(plan.TrunkPrefix):]}, nil
+	}
+	return Number{}, ErrInvalidNumber
+}
+
+// parseInternational parses the digits of an international number, country
+// code first. Country codes are prefix-free, so at most one country code
+// matches; the plans sharing it are tried in order.
+func (parser *Parser) parseInternational(number string) (Number, error) {
+	known := false
+	for i := range parser.plans {
+		plan := &parser.plans[i]
+		if !strings.HasPrefix(number, plan.CountryCode) {
+			continue
+		}
+
+		known = true
+		national := number[len(plan.CountryCode):]
+		if plan.valid(national) {
+			return Number{Region: plan.Region, CountryCode: plan.CountryCode, National: national}, nil
+		}
+	}
+
+	if !known {
+		return Number{}, ErrUnknownCountryCode
+	}
+	return Number{}, ErrInvalidNumber
+}
+
+// Format lays out number for display in the default region, see FormatIn.
+func (parser *Parser) Format(number Number) string {
+	return parser.FormatIn(number, parser.defaultRegion)
+}
+
+// FormatIn lays out number for display in region: in the national format of
+// its plan when it belongs to region, and in the international format
+// otherwise.
+func (parser *Parser) FormatIn(number Number, region string) string {
+	plan, ok := parser.byRegion[number.Region]
+	if !ok {
+		return number.E164()
+	}
+
+	national := number.Region == region
+	for _, format := range plan.Formats {
+		pattern := format.International
+		if national {
+			pattern = format.National
+		}
+		if !strings.HasPrefix(number.National, format.Leading) || strings.Count(pattern, "#") != len(number.National) {
+			continue
+		}
+
+		laidOut := layout(pattern, number.National)
+		if national {
+			return laidOut
+		}
+		return "+" + number.CountryCode + " " + laidOut
+	}
+
+	if national {
+		return plan.TrunkPrefix + number.National
+	}
+	return "+" + number.CountryCode + " " + number.National
+}
+
+// layout replaces the # placeholders of pattern with digits, in order. The
+// pattern must have one placeholder per digit.
+func layout(pattern string, digits string) string {
+	var b strings.Builder
+	next := 0
+	for _, r := range pattern {
+		if r == '#' {
+			b.WriteByte(digits[next])
+			next++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestParser(t *testing.T, defaultRegion string) *Parser {
+	t.Helper()
+
+	parser, err := NewParser(defaultRegion, Plans)
+	if err != nil {
+		t.Fatalf("NewParser(%q): %v", defaultRegion, err)
+	}
+	return parser
+}
+
+func TestParseIn(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   Number
+	}{
+		{"US national", "(415) 555-2671", "US", Number{"US", "1", "4155552671"}},
+		{"US trunk prefix", "1 415 555 2671", "US", Number{"US", "1", "4155552671"}},
+		{"US plus", "+1 415-555-2671", "GB", Number{"US", "1", "4155552671"}},
+		{"US dots", "415.555.2671", "US", Number{"US", "1", "4155552671"}},
+		{"GB from US international prefix", "011 44 20 7946 0958", "US", Number{"GB", "44", "2079460958"}},
+		{"GB London", "020 7946 0958", "GB", Number{"GB", "44", "2079460958"}},
+		{"GB mobile", "07911 123456", "GB", Number{"GB", "44", "7911123456"}},
+		{"GB nine digits", "01632 96072", "GB", Number{"GB", "44", "163296072"}},
+		{"DE Berlin", "030 12345678", "DE", Number{"DE", "49", "3012345678"}},
+		{"DE mobile", "0171 1234567", "DE", Number{"DE", "49", "1711234567"}},
+		{"DE from GB", "00 49 89 12345678", "GB", Number{"DE", "49", "8912345678"}},
+		{"FR", "01 23 45 67 89", "FR", Number{"FR", "33", "123456789"}},
+		{"IN", "098765 43210", "IN", Number{"IN", "91", "9876543210"}},
+		{"AU mobile", "0412 345 678", "AU", Number{"AU", "61", "412345678"}},
+		{"AU from AU international prefix", "0011 44 20 7946 0958", "AU", Number{"GB", "44", "2079460958"}},
+		{"KZ national", "8 701 234 5678", "KZ", Number{"KZ", "7", "7012345678"}},
+		{"KZ plus", "+7 701 234-56-78", "RU", Number{"KZ", "7", "7012345678"}},
+		{"RU plus", "+7 916 123-45-67", "KZ", Number{"RU", "7", "9161234567"}},
+		{"RU national", "8 (495) 123-45-67", "RU", Number{"RU", "7", "4951234567"}},
+		{"RU from KZ international prefix", "810 7 916 123 45 67", "KZ", Number{"RU", "7", "9161234567"}},
+		{"slashes and tabs", "030/1234\t5678", "DE", Number{"DE", "49", "3012345678"}},
+	}
+
+	parser := newTestParser(t, "US")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseIn(tt.raw, tt.region)
+			if err != nil {
+				t.Fatalf("ParseIn(%q, %q): %v", tt.raw, tt.region, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseIn(%q, %q) = %+v, want %+v", tt.raw, tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   error
+	}{
+		{"letters", "abc", "US", ErrInvalidCharacters},
+		{"brackets", "[123]", "US", ErrInvalidCharacters},
+		{"plus inside", "1+415 555 2671", "US", ErrInvalidCharacters},
+		{"empty", "", "US", ErrInvalidNumber},
+		{"separators only", " - ", "US", ErrInvalidNumber},
+		{"too many digits", "+1 4155552671 123456789", "US", ErrInvalidNumber},
+		{"too short", "5", "US", ErrInvalidNumber},
+		{"US too short", "415 555 267", "US", ErrInvalidNumber},
+		{"US too long", "415 555 26711", "US", ErrInvalidNumber},
+		{"US leading 1", "+1 115 555 2671", "US", ErrInvalidNumber},
+		{"US leading 0", "015 555 2671", "US", ErrInvalidNumber},
+		{"GB leading 4", "041 2345 6789", "GB", ErrInvalidNumber},
+		{"FR too long", "01 23 45 67 890", "FR", ErrInvalidNumber},
+		{"AU leading 5", "0512 345 678", "AU", ErrInvalidNumber},
+		{"+7 leading 5", "+7 512 345 6789", "US", ErrInvalidNumber},
+		{"unknown country code", "+999 1234", "US", ErrUnknownCountryCode},
+		{"unknown country code after prefix", "011 999 1234", "US", ErrUnknownCountryCode},
+		{"unknown region", "415 555 2671", "ZZ", ErrUnknownRegion},
+	}
+
+	parser := newTestParser(t, "US")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseIn(tt.raw, tt.region)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseIn(%q, %q) = %+v, %v, want error %v", tt.raw, tt.region, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUsesDefaultRegion(t *testing.T) {
+	parser := newTestParser(t, "GB")
+
+	got, err := parser.Parse("020 7946 0958")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want := (Number{"GB", "44", "2079460958"}); got != want {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+	if region := parser.DefaultRegion(); region != "GB" {
+		t.Errorf("DefaultRegion = %q, want %q", region, "GB")
+	}
+}
+
+func TestE164(t *testing.T) {
+	tests := []struct {
+		number Number
+		want   string
+	}{
+		{Number{"US", "1", "4155552671"}, "+14155552671"},
+		{Number{"GB", "44", "2079460958"}, "+442079460958"},
+		{Number{"KZ", "7", "7012345678"}, "+77012345678"},
+		{Number{"AU", "61", "412345678"}, "+61412345678"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.number.E164(); got != tt.want {
+			t.Errorf("%+v.E164() = %q, want %q", tt.number, got, tt.want)
+		}
+		if got := tt.number.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestFormatIn(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		national      string
+		international string
+	}{
+		{"US", "+1 415 555 2671", "(415) 555-2671", "+1 415-555-2671"},
+		{"GB London", "+44 20 7946 0958", "020 7946 0958", "+44 20 7946 0958"},
+		{"GB area code", "+44 1632 960720", "01632 960720", "+44 1632 960720"},
+		{"GB mobile", "+44 7911 123456", "07911 123456", "+44 7911 123456"},
+		{"GB other", "+44 845 678 9012", "0845 678 9012", "+44 845 678 9012"},
+		{"GB nine digits", "+44 1632 96072", "0163296072", "+44 163296072"},
+		{"DE Berlin", "+49 30 12345678", "030 12345678", "+49 30 12345678"},
+		{"DE Hamburg", "+49 40 12345678", "040 12345678", "+49 40 12345678"},
+		{"DE Munich", "+49 89 12345678", "089 12345678", "+49 89 12345678"},
+		{"DE mobile eleven digits", "+49 151 12345678", "0151 12345678", "+49 151 12345678"},
+		{"DE mobile ten digits", "+49 171 1234567", "0171 1234567", "+49 171 1234567"},
+		{"DE unformatted", "+49 221 123456", "0221123456", "+49 221123456"},
+		{"FR", "+33 1 23 45 67 89", "01 23 45 67 89", "+33 1 23 45 67 89"},
+		{"IN", "+91 98765 43210", "098765 43210", "+91 98765 43210"},
+		{"AU mobile", "+61 412 345 678", "0412 345 678", "+61 412 345 678"},
+		{"AU landline", "+61 2 1234 5678", "02 1234 5678", "+61 2 1234 5678"},
+		{"KZ", "+7 701 234 56 78", "8 (701) 234-56-78", "+7 701 234-56-78"},
+		{"RU", "+7 916 123 45 67", "8 (916) 123-45-67", "+7 916 123-45-67"},
+	}
+
+	parser := newTestParser(t, "US")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := parser.Parse(tt.raw)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.raw, err)
+			}
+
+			if got := parser.FormatIn(number, number.Region); got != tt.national {
+				t.Errorf("FormatIn(%+v, %q) = %q, want %q", number, number.Region, got, tt.national)
+			}
+
+			other := "US"
+			if number.Region == "US" {
+				other = "GB"
+			}
+			if got := parser.FormatIn(number, other); got != tt.international {
+				t.Errorf("FormatIn(%+v, %q) = %q, want %q", number, other, got, tt.international)
+			}
+		})
+	}
+}
+
+func TestFormatUsesDefaultRegion(t *testing.T) {
+	parser := newTestParser(t, "KZ")
+
+	kz, err := parser.Parse("8 701 234 5678")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := parser.Format(kz), "8 (701) 234-56-78"; got != want {
+		t.Errorf("Format(%+v) = %q, want %q", kz, got, want)
+	}
+
+	// RU shares the country code of KZ, but not its region.
+	ru, err := parser.Parse("+7 916 123 45 67")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := parser.Format(ru), "+7 916 123-45-67"; got != want {
+		t.Errorf("Format(%+v) = %q, want %q", ru, got, want)
+	}
+}
+
+func TestFormatInUnknownRegion(t *testing.T) {
+	parser, err := NewParser("US", Plans[:1])
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+
+	number := Number{Region: "GB", CountryCode: "44", National: "2079460958"}
+	if got, want := parser.FormatIn(number, "US"), "+442079460958"; got != want {
+		t.Errorf("FormatIn(%+v) = %q, want %q", number, got, want)
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	if _, err := NewParser("ZZ", Plans); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("NewParser with unknown default region: %v, want %v", err, ErrUnknownRegion)
+	}
+	if _, err := NewParser("US", append([]Plan{Plans[0]}, Plans...)); err == nil {
+		t.Error("NewParser with duplicate plans: got no error")
+	}
+}
+
+func TestPlansFor(t *testing.T) {
+	plans, err := PlansFor([]string{" kz", "US "})
+	if err != nil {
+		t.Fatalf("PlansFor: %v", err)
+	}
+	if len(plans) != 2 || plans[0].Region != "KZ" || plans[1].Region != "US" {
+		t.Errorf("PlansFor = %+v, want KZ and US", plans)
+	}
+
+	if _, err := PlansFor([]string{"US", "ZZ"}); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("PlansFor with unknown region: %v, want %v", err, ErrUnknownRegion)
+	}
+
+	if got := Regions(); len(got) != len(Plans) || got[0] != "US" {
+		t.Errorf("Regions = %v", got)
+	}
+}
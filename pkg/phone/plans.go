@@ -0,0 +1,138 @@
+package phone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plans lists the built-in numbering plans. They cover the common fixed and
+// mobile ranges of each region rather than every rule of its plan.
+var Plans = []Plan{
+	{
+		Region:              "US",
+		CountryCode:         "1",
+		InternationalPrefix: "011",
+		TrunkPrefix:         "1",
+		Lengths:             []int{10},
+		Leading:             []string{"2", "3", "4", "5", "6", "7", "8", "9"},
+		Formats: []Format{
+			{National: "(###) ###-####", International: "###-###-####"},
+		},
+	},
+	{
+		Region:              "GB",
+		CountryCode:         "44",
+		InternationalPrefix: "00",
+		TrunkPrefix:         "0",
+		Lengths:             []int{9, 10},
+		Leading:             []string{"1", "2", "3", "5", "7", "8", "9"},
+		Formats: []Format{
+			{Leading: "2", National: "0## #### ####", International: "## #### ####"},
+			{Leading: "1", National: "0#### ######", International: "#### ######"},
+			{Leading: "7", National: "0#### ######", International: "#### ######"},
+			{National: "0### ### ####", International: "### ### ####"},
+		},
+	},
+	{
+		Region:              "DE",
+		CountryCode:         "49",
+		InternationalPrefix: "00",
+		TrunkPrefix:         "0",
+		Lengths:             []int{6, 7, 8, 9, 10, 11},
+		Leading:             []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		Formats: []Format{
+			{Leading: "1", National: "0### ########", International: "### ########"},
+			{Leading: "1", National: "0### #######", International: "### #######"},
+			{Leading: "30", National: "0## ########", International: "## ########"},
+			{Leading: "40", National: "0## ########", International: "## ########"},
+			{Leading: "89", National: "0## ########", International: "## ########"},
+		},
+	},
+	{
+		Region:              "FR",
+		CountryCode:         "33",
+		InternationalPrefix: "00",
+		TrunkPrefix:         "0",
+		Lengths:             []int{9},
+		Leading:             []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		Formats: []Format{
+			{National: "0# ## ## ## ##", International: "# ## ## ## ##"},
+		},
+	},
+	{
+		Region:              "IN",
+		CountryCode:         "91",
+		InternationalPrefix: "00",
+		TrunkPrefix:         "0",
+		Lengths:             []int{10},
+		Leading:             []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		Formats: []Format{
+			{National: "0##### #####", International: "##### #####"},
+		},
+	},
+	{
+		Region:              "AU",
+		CountryCode:         "61",
+		InternationalPrefix: "0011",
+		TrunkPrefix:         "0",
+		Lengths:             []int{9},
+		Leading:             []string{"2", "3", "4", "7", "8"},
+		Formats: []Format{
+			{Leading: "4", National: "0### ### ###", International: "### ### ###"},
+			{National: "0# #### ####", International: "# #### ####"},
+		},
+	},
+	{
+		Region:              "KZ",
+		CountryCode:         "7",
+		InternationalPrefix: "810",
+		TrunkPrefix:         "8",
+		Lengths:             []int{10},
+		Leading:             []string{"6", "7"},
+		Formats: []Format{
+			{National: "8 (###) ###-##-##", International: "### ###-##-##"},
+		},
+	},
+	{
+		Region:              "RU",
+		CountryCode:         "7",
+		InternationalPrefix: "810",
+		TrunkPrefix:         "8",
+		Lengths:             []int{10},
+		Leading:             []string{"3", "4", "8", "9"},
+		Formats: []Format{
+			{National: "8 (###) ###-##-##", International: "### ###-##-##"},
+		},
+	},
+}
+
+// Regions returns the regions of the built-in plans, in order.
+func Regions() []string {
+	regions := make([]string, len(Plans))
+	for i, plan := range Plans {
+		regions[i] = plan.Region
+	}
+	return regions
+}
+
+// PlansFor returns the built-in plans of the given regions, in order. Region
+// codes are case-insensitive.
+func PlansFor(regions []string) ([]Plan, error) {
+	plans := make([]Plan, 0, len(regions))
+	for _, region := range regions {
+		region = strings.ToUpper(strings.TrimSpace(region))
+
+		found := false
+		for _, plan := range Plans {
+			if plan.Region == region {
+				plans = append(plans, plan)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w %q", ErrUnknownRegion, region)
+		}
+	}
+	return plans, nil
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PingCheck is a health.CheckFunc verifying that the database answers.
+func PingCheck(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// MigrationCheck is a health.CheckFunc failing while the schema is behind the
+// migrations embedded in the binary.
+func MigrationCheck(migrator *Migrator) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if latest := migrator.Latest(); version != latest {
+			return fmt.Errorf("schema version is %d, expected %d", version, latest)
+		}
+		return nil
+	}
+}
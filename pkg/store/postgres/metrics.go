@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"advanced.microservices/pkg/metrics"
+)
+
+// RegisterStats exposes the connection pool statistics of db. The values are
+// read from db.Stats() on every scrape.
+func RegisterStats(registry *metrics.Registry, db *sql.DB) {
+	stat := func(fn func(stats sql.DBStats) float64) func() float64 {
+		return func() float64 {
+			return fn(db.Stats())
+		}
+	}
+
+	metrics.NewGaugeFunc(registry, "db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	metrics.NewGaugeFunc(registry, "db_open_connections", "Number of established connections, both in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	metrics.NewGaugeFunc(registry, "db_in_use_connections", "Number of connections currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	metrics.NewGaugeFunc(registry, "db_idle_connections", "Number of idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	metrics.NewCounterFunc(registry, "db_wait_count_total", "Total number of connections waited for.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	metrics.NewCounterFunc(registry, "db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	metrics.NewCounterFunc(registry, "db_max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	metrics.NewCounterFunc(registry, "db_max_idle_time_closed_total", "Total number of connections closed due to SetConnMaxIdleTime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }))
+	metrics.NewCounterFunc(registry, "db_max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
@@ -0,0 +1,374 @@
+package vcard
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrMissingBegin = errors.New("vcard: property outside of BEGIN:VCARD/END:VCARD")
+	ErrUnterminated = errors.New("vcard: missing END:VCARD")
+)
+
+type Property struct {
+	Name   string
+	Params map[string][]string
+	Value  string
+	raw    string
+}
+
+// Components splits a structured value such as N or ADR on its unescaped
+// semicolons.
+func (p Property) Components() []string {
+	raw := p.raw
+	if raw == "" {
+		raw = escape(p.Value)
+	}
+
+	var components []string
+	start := 0
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case ';':
+			components = append(components, unescape(raw[start:i]))
+			start = i + 1
+		}
+	}
+	return append(components, unescape(raw[start:]))
+}
+
+// Card is a single vCard. Properties keep their original order; names are
+// upper-cased.
+type Card struct {
+	Properties []Property
+}
+
+// Get returns the first property with the given name.
+func (c Card) Get(name string) (Property, bool) {
+	name = strings.ToUpper(name)
+	for _, property := range c.Properties {
+		if property.Name == name {
+			return property, true
+		}
+	}
+	return Property{}, false
+}
+
+// All returns every property with the given name.
+func (c Card) All(name string) []Property {
+	name = strings.ToUpper(name)
+	var properties []Property
+	for _, property := range c.Properties {
+		if property.Name == name {
+			properties = append(properties, property)
+		}
+	}
+	return properties
+}
+
+// Value returns the value of the first property with the given name, or an
+// empty string.
+func (c Card) Value(name string) string {
+	property, _ := c.Get(name)
+	return property.Value
+}
+
+func (c *Card) Add(name string, value string, params map[string][]string) {
+	c.Properties = append(c.Properties, Property{Name: strings.ToUpper(name), Params: params, Value: value})
+}
+
+// AddProperty appends a prepared property, such as one built by Structured.
+func (c *Card) AddProperty(name string, property Property) {
+	property.Name = strings.ToUpper(name)
+	c.Properties = append(c.Properties, property)
+}
+
+// Decoder reads vCard 3.0 and 4.0 cards one at a time.
+type Decoder struct {
+	scanner *bufio.Scanner
+	pending string
+	// scanned counts the physical lines read, pending included; line is the
+	// last physical line of the logical line returned by next.
+	scanned int
+	line    int
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &Decoder{scanner: scanner}
+}
+
+// Line returns the line number at which the last decoded card ended.
+func (d *Decoder) Line() int {
+	return d.line
+}
+
+// Decode returns the next card, or io.EOF when the input is exhausted.
+func (d *Decoder) Decode() (Card, error) {
+	var (
+		card   Card
+		inCard bool
+	)
+
+	for {
+		line, err := d.next()
+		if err == io.EOF {
+			if inCard {
+				return Card{}, ErrUnterminated
+			}
+			return Card{}, io.EOF
+		}
+		if err != nil {
+			return Card{}, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		property, err := parseLine(line)
+		if err != nil {
+			return Card{}, fmt.Errorf("vcard: line %d: %w", d.line, err)
+		}
+
+		switch {
+		case property.Name == "BEGIN" && strings.EqualFold(property.Value, "VCARD"):
+			inCard = true
+			card = Card{}
+		case property.Name == "END" && strings.EqualFold(property.Value, "VCARD"):
+			if !inCard {
+				return Card{}, ErrMissingBegin
+			}
+			return card, nil
+		case !inCard:
+			return Card{}, ErrMissingBegin
+		default:
+			card.Properties = append(card.Properties, property)
+		}
+	}
+}
+
+// next returns the next logical line, joining folded continuation lines.
+func (d *Decoder) next() (string, error) {
+	var line string
+	if d.pending != "" {
+		line, d.pending = d.pending, ""
+	} else {
+		if !d.scanner.Scan() {
+			if err := d.scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		d.scanned++
+		line = strings.TrimRight(d.scanner.Text(), "\r")
+	}
+	d.line = d.scanned
+
+	for d.scanner.Scan() {
+		d.scanned++
+		next := strings.TrimRight(d.scanner.Text(), "\r")
+		if strings.HasPrefix(next, " ") || strings.HasPrefix(next, "\t") {
+			line += next[1:]
+			d.line = d.scanned
+			continue
+		}
+		d.pending = next
+		break
+	}
+
+	return line, d.scanner.Err()
+}
+
+func parseLine(line string) (Property, error) {
+	colon := indexUnquoted(line, ':')
+	if colon < 0 {
+		return Property{}, errors.New("missing ':' separator")
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := splitUnquoted(head, ';')
+
+	name := strings.ToUpper(parts[0])
+	// Grouped properties such as "item1.TEL" are treated as plain properties.
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		name = name[dot+1:]
+	}
+	if name == "" {
+		return Property{}, errors.New("missing property name")
+	}
+
+	property := Property{Name: name, Value: unescape(value), raw: value}
+
+	for _, param := range parts[1:] {
+		key, values, found := strings.Cut(param, "=")
+		key = strings.ToUpper(key)
+		if property.Params == nil {
+			property.Params = make(map[string][]string)
+		}
+		if !found {
+			// vCard 2.1 style bare parameters, e.g. TEL;CELL:...
+			property.Params["TYPE"] = append(property.Params["TYPE"], strings.ToLower(key))
+			continue
+		}
+		for _, value := range splitUnquoted(values, ',') {
+			property.Params[key] = append(property.Params[key], strings.Trim(value, `"`))
+		}
+	}
+
+	return property, nil
+}
+
+func indexUnquoted(s string, sep byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexUnquoted(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`).Replace(s)
+}
+
+// Encoder writes cards with CRLF line endings, escaping values and folding
+// lines longer than 75 octets as required by RFC 6350.
+type Encoder struct {
+	w       io.Writer
+	version string
+}
+
+// NewEncoder returns an encoder producing cards of the given version, either
+// "3.0" or "4.0".
+func NewEncoder(w io.Writer, version string) *Encoder {
+	return &Encoder{w: w, version: version}
+}
+
+func (e *Encoder) Encode(card Card) error {
+	var b strings.Builder
+
+	b.WriteString("BEGIN:VCARD\r\n")
+	b.WriteString("VERSION:" + e.version + "\r\n")
+
+	for _, property := range card.Properties {
+		if property.Name == "VERSION" || property.Name == "BEGIN" || property.Name == "END" {
+			continue
+		}
+		b.WriteString(fold(contentLine(property)))
+	}
+
+	b.WriteString("END:VCARD\r\n")
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func contentLine(property Property) string {
+	var b strings.Builder
+	b.WriteString(property.Name)
+
+	keys := make([]string, 0, len(property.Params))
+	for key := range property.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		b.WriteString(";" + key + "=")
+		for i, value := range property.Params[key] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if strings.ContainsAny(value, ":;,") {
+				value = `"` + strings.ReplaceAll(value, `"`, "") + `"`
+			}
+			b.WriteString(value)
+		}
+	}
+
+	b.WriteByte(':')
+	if property.raw != "" {
+		b.WriteString(property.raw)
+	} else {
+		b.WriteString(escape(property.Value))
+	}
+	return b.String()
+}
+
+// fold splits a content line into chunks of at most 75 octets without
+// breaking UTF-8 sequences.
+func fold(line string) string {
+	const limit = 75
+
+	var b strings.Builder
+	width := limit
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		width = limit - 1
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
+
+// Structured joins the components of a structured value such as N or ADR,
+// escaping each of them.
+func Structured(components ...string) Property {
+	escaped := make([]string, len(components))
+	for i, component := range components {
+		escaped[i] = escape(component)
+	}
+	return Property{Value: strings.Join(components, ";"), raw: strings.Join(escaped, ";")}
+}
@@ -0,0 +1,340 @@
+package vcard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// property is a decoded property without its raw value, for comparison.
+type property struct {
+	Name   string
+	Params map[string][]string
+	Value  string
+}
+
+func properties(card Card) []property {
+	result := []property{}
+	for _, p := range card.Properties {
+		if p.Name == "VERSION" {
+			continue
+		}
+		result = append(result, property{Name: p.Name, Params: p.Params, Value: p.Value})
+	}
+	return result
+}
+
+func roundTrip(t *testing.T, version string, card Card) (Card, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, version).Encode(card); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	encoded := buf.String()
+
+	decoder := NewDecoder(strings.NewReader(encoded))
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode(%q): %v", encoded, err)
+	}
+	if _, err := decoder.Decode(); err != io.EOF {
+		t.Fatalf("second Decode: %v, want io.EOF", err)
+	}
+	if got := decoded.Value("VERSION"); got != version {
+		t.Errorf("VERSION = %q, want %q", got, version)
+	}
+
+	return decoded, encoded
+}
+
+func TestRoundTrip(t *testing.T) {
+	long := strings.Repeat("Notes that go on and on, ", 8)
+	multibyte := strings.Repeat("Привет, мир; ", 10)
+
+	tests := []struct {
+		name       string
+		properties []property
+	}{
+		{
+			name: "plain",
+			properties: []property{
+				{Name: "FN", Value: "Ann Bee Cee"},
+				{Name: "ORG", Value: "Acme"},
+			},
+		},
+		{
+			name: "escaping",
+			properties: []property{
+				{Name: "NOTE", Value: "Line one\nLine two; with, punctuation and a \\ backslash"},
+				{Name: "TITLE", Value: `Head of R\D, EMEA`},
+			},
+		},
+		{
+			name: "parameters",
+			properties: []property{
+				{Name: "TEL", Params: map[string][]string{"TYPE": {"cell", "voice"}, "PREF": {"1"}}, Value: "+1 202 555 0100"},
+				{Name: "EMAIL", Params: map[string][]string{"TYPE": {"work"}}, Value: "ann@example.com"},
+				{Name: "X-LABEL", Params: map[string][]string{"LABEL": {"Home: 1st floor, left"}}, Value: "x"},
+			},
+		},
+		{
+			name: "folding",
+			properties: []property{
+				{Name: "NOTE", Value: long},
+			},
+		},
+		{
+			name: "folding multibyte",
+			properties: []property{
+				{Name: "NOTE", Value: multibyte},
+			},
+		},
+	}
+
+	for _, version := range []string{"3.0", "4.0"} {
+		for _, tt := range tests {
+			t.Run(version+" "+tt.name, func(t *testing.T) {
+				var card Card
+				for _, p := range tt.properties {
+					card.Add(p.Name, p.Value, p.Params)
+				}
+
+				decoded, encoded := roundTrip(t, version, card)
+
+				if got := properties(decoded); !reflect.DeepEqual(got, tt.properties) {
+					t.Errorf("round trip = %+v, want %+v\nencoded:\n%s", got, tt.properties, encoded)
+				}
+			})
+		}
+	}
+}
+
+func TestEncodeFolds(t *testing.T) {
+	var card Card
+	card.Add("NOTE", strings.Repeat("Привет, мир; ", 20), nil)
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, "4.0").Encode(card); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	encoded := buf.String()
+	if !strings.HasSuffix(encoded, "\r\n") {
+		t.Errorf("encoded card does not end with CRLF: %q", encoded)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(encoded, "\r\n"), "\r\n")
+	if len(lines) < 5 {
+		t.Fatalf("got %d lines, want the note folded:\n%s", len(lines), encoded)
+	}
+	for i, line := range lines {
+		if len(line) > 75 {
+			t.Errorf("line %d is %d octets long, want at most 75", i+1, len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line %d splits a UTF-8 sequence: %q", i+1, line)
+		}
+	}
+	for i, line := range lines[3 : len(lines)-1] {
+		if !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line %d does not start with a space: %q", i+4, line)
+		}
+	}
+}
+
+func TestStructuredRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []string
+	}{
+		{"name", []string{"Cee", "Ann", "Bee", "Dr.", ""}},
+		{"escaped components", []string{"O;Brien", "Ann, Jr.", `back\slash`, "", "line\nbreak"}},
+		{"address", []string{"", "Suite 5", "1 Main St", "Springfield", "IL", "62701", "USA"}},
+	}
+
+	for _, version := range []string{"3.0", "4.0"} {
+		for _, tt := range tests {
+			t.Run(version+" "+tt.name, func(t *testing.T) {
+				var card Card
+				card.AddProperty("N", Structured(tt.components...))
+
+				decoded, encoded := roundTrip(t, version, card)
+
+				n, ok := decoded.Get("n")
+				if !ok {
+					t.Fatalf("decoded card has no N:\n%s", encoded)
+				}
+				if got := n.Components(); !reflect.DeepEqual(got, tt.components) {
+					t.Errorf("Components = %q, want %q\nencoded:\n%s", got, tt.components, encoded)
+				}
+			})
+		}
+	}
+}
+
+func TestComponentsOfPlainValue(t *testing.T) {
+	p := Property{Name: "N", Value: "Cee;Ann"}
+	if got, want := p.Components(), []string{"Cee;Ann"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Components = %q, want %q", got, want)
+	}
+}
+
+func TestTelURI(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:Ann Bee Cee\r\n" +
+		"TEL;VALUE=uri;TYPE=voice,cell;PREF=1:tel:+1-202-555-0100;ext=42\r\n" +
+		"TEL;VALUE=uri;TYPE=\"work,main\":tel:+44-20-7946-0958\r\n" +
+		"END:VCARD\r\n"
+
+	card, err := NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	tels := card.All("TEL")
+	if len(tels) != 2 {
+		t.Fatalf("got %d TEL properties, want 2", len(tels))
+	}
+	if got, want := tels[0].Value, "tel:+1-202-555-0100;ext=42"; got != want {
+		t.Errorf("Value = %q, want %q", got, want)
+	}
+	want := map[string][]string{"VALUE": {"uri"}, "TYPE": {"voice", "cell"}, "PREF": {"1"}}
+	if !reflect.DeepEqual(tels[0].Params, want) {
+		t.Errorf("Params = %v, want %v", tels[0].Params, want)
+	}
+	// A quoted parameter value is a single value, commas included.
+	if got, want := tels[1].Params["TYPE"], []string{"work,main"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TYPE = %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, "4.0").Encode(card); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if encoded := buf.String(); !strings.Contains(encoded, ":tel:+1-202-555-0100;ext=42\r\n") {
+		t.Errorf("encoded URI was altered:\n%s", encoded)
+	}
+
+	decoded, err := NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got := properties(decoded); !reflect.DeepEqual(got, properties(card)) {
+		t.Errorf("round trip = %+v, want %+v", got, properties(card))
+	}
+}
+
+func TestDecodeFolded(t *testing.T) {
+	input := "BEGIN:VCARD\n" +
+		"VERSION:3.0\n" +
+		"NOTE:This note was fol\n" +
+		" ded with a space and\n" +
+		"\t a tab.\n" +
+		"FN:Ann Bee Cee\n" +
+		"END:VCARD\n"
+
+	decoder := NewDecoder(strings.NewReader(input))
+	card, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if got, want := card.Value("NOTE"), "This note was folded with a space and a tab."; got != want {
+		t.Errorf("NOTE = %q, want %q", got, want)
+	}
+	if got, want := card.Value("FN"), "Ann Bee Cee"; got != want {
+		t.Errorf("FN = %q, want %q", got, want)
+	}
+	if got, want := decoder.Line(), 7; got != want {
+		t.Errorf("Line = %d, want %d", got, want)
+	}
+}
+
+func TestDecodeProperties(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"item1.EMAIL;type=INTERNET:ann@example.com\r\n" +
+		"TEL;CELL;HOME:202 555 0100\r\n" +
+		"end:vcard\r\n"
+
+	card, err := NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	email, ok := card.Get("email")
+	if !ok || email.Value != "ann@example.com" || !reflect.DeepEqual(email.Params, map[string][]string{"TYPE": {"INTERNET"}}) {
+		t.Errorf("EMAIL = %+v, want the grouped property", email)
+	}
+
+	tel, _ := card.Get("TEL")
+	if want := map[string][]string{"TYPE": {"cell", "home"}}; !reflect.DeepEqual(tel.Params, want) {
+		t.Errorf("TEL params = %v, want %v", tel.Params, want)
+	}
+}
+
+func TestDecodeMultipleCards(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf, "3.0")
+	for _, name := range []string{"Ann Bee Cee", "Dan Eve Fay"} {
+		var card Card
+		card.Add("FN", name, nil)
+		if err := encoder.Encode(card); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+	}
+
+	decoder := NewDecoder(strings.NewReader("\r\n" + buf.String()))
+	var names []string
+	var lines []int
+	for {
+		card, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		names = append(names, card.Value("FN"))
+		lines = append(lines, decoder.Line())
+	}
+
+	if want := []string{"Ann Bee Cee", "Dan Eve Fay"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+	if want := []int{5, 9}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Line after each card = %v, want %v", lines, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"property before BEGIN", "FN:Ann Bee Cee\r\nEND:VCARD\r\n", ErrMissingBegin},
+		{"END without BEGIN", "END:VCARD\r\n", ErrMissingBegin},
+		{"missing END", "BEGIN:VCARD\r\nFN:Ann Bee Cee\r\n", ErrUnterminated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDecoder(strings.NewReader(tt.input)).Decode()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Decode = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	_, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\nno separator\r\nEND:VCARD\r\n")).Decode()
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Decode of a line without ':' = %v, want an error on line 2", err)
+	}
+}
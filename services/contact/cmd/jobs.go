@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"advanced.microservices/pkg/jsonlog"
+)
+
+// purgeTrash permanently removes contacts that have been in the trash for
+// longer than the configured retention, once at startup and then every purge
+// interval, until ctx is done.
+func (service *service) purgeTrash(ctx context.Context) {
+	ticker := time.NewTicker(service.config.trash.interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := service.contacts.PurgeDeleted(ctx, service.config.trash.retention)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			service.logger.Error("purging trash failed", jsonlog.Err(err))
+		case purged > 0:
+			service.logger.Info("purged trash", jsonlog.Int64("contacts", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// phoneBackfillBatch is the number of contacts backfillPhoneE164 fills at
+// once.
+const phoneBackfillBatch = 500
+
+// backfillPhoneE164 fills in the E.164 forms of the numbers stored before
+// they were kept, once at startup, so that they match newer numbers when
+// looking for duplicates.
+func (service *service) backfillPhoneE164(ctx context.Context) {
+	filled, err := service.contacts.BackfillPhoneE164(ctx, phoneBackfillBatch)
+	switch {
+	case err != nil && ctx.Err() == nil:
+		service.logger.Error("backfilling phone numbers failed", jsonlog.Err(err), jsonlog.Int64("contacts", filled))
+	case filled > 0:
+		service.logger.Info("backfilled phone numbers", jsonlog.Int64("contacts", filled))
+	}
+}
+
+// relayOutbox publishes the pending domain events every outbox interval until
+// ctx is done. Full batches are followed by the next one right away, so that
+// a backlog drains without waiting for the ticker.
+func (service *service) relayOutbox(ctx context.Context) {
+	ticker := time.NewTicker(service.config.outbox.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := service.outbox.Relay(ctx, service.config.outbox.batch)
+			if err != nil && ctx.Err() == nil {
+				service.logger.Error("relaying outbox failed", jsonlog.Err(err), jsonlog.Int("published", published))
+			}
+			if err != nil || published < service.config.outbox.batch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// webhookBatch is the number of deliveries a webhook worker claims at once.
+// It is kept small so that a slow receiver delays few other deliveries.
+const webhookBatch = 10
+
+// deliverWebhooks attempts the due webhook deliveries every poll interval
+// until ctx is done. Like relayOutbox, it goes on with the next batch right
+// away after a full one. Several workers run it side by side.
+func (service *service) deliverWebhooks(ctx context.Context) {
+	ticker := time.NewTicker(service.config.webhook.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			attempted, err := service.webhooks.Deliver(ctx, webhookBatch)
+			if err != nil && ctx.Err() == nil {
+				service.logger.Error("delivering webhooks failed", jsonlog.Err(err), jsonlog.Int("attempted", attempted))
+			}
+			if err != nil || attempted < webhookBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
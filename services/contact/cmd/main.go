@@ -1,71 +1,362 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
+	"math"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"advanced.microservices/pkg/health"
 	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/metrics"
+	"advanced.microservices/pkg/phone"
 	"advanced.microservices/pkg/store"
 	"advanced.microservices/pkg/store/postgres"
 	"advanced.microservices/services/contact/internal/delivery"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/publisher"
 	"advanced.microservices/services/contact/internal/repository"
 	"advanced.microservices/services/contact/internal/useCase"
+	"advanced.microservices/services/contact/migrations"
 	"github.com/julienschmidt/httprouter"
+	"google.golang.org/grpc"
 )
 
+// version is reported by the health endpoints. Release builds override it
+// with -ldflags "-X main.version=...".
+var version = "1.0.0"
+
 type config struct {
-	port int
-	env  string
-	db   store.DbConfig
+	port     int
+	grpcPort int
+	env      string
+	db       store.DbConfig
+	store    string
+	migrate  string
+	limiter  delivery.LimiterConfig
+	drain    time.Duration
+	trash    struct {
+		retention time.Duration
+		interval  time.Duration
+	}
+	outbox struct {
+		publisher   string
+		file        string
+		interval    time.Duration
+		batch       int
+		maxAttempts int
+		backoff     time.Duration
+		maxBackoff  time.Duration
+	}
+	webhook struct {
+		workers      int
+		pollInterval time.Duration
+		timeout      time.Duration
+		maxAttempts  int
+		backoff      time.Duration
+		maxBackoff   time.Duration
+		disableAfter int
+	}
+	phone struct {
+		region  string
+		regions string
+	}
+	log struct {
+		level            string
+		stackTraces      bool
+		sampleFirst      int
+		sampleThereafter int
+	}
+
+	metricsAddr string
 }
 
 type service struct {
-	config config
-	logger *jsonlog.Logger
-	wg     *sync.WaitGroup
-	db     *sql.DB
-	router *httprouter.Router
+	config     config
+	logger     *jsonlog.Logger
+	wg         *sync.WaitGroup
+	db         *sql.DB
+	router     *httprouter.Router
+	middleware *delivery.Middleware
+	limiter    *delivery.RateLimiter
+	metrics    *delivery.Metrics
+	registry   *metrics.Registry
+	contacts   domain.ContactUseCase
+	outbox     domain.OutboxUseCase
+	webhooks   domain.WebhookUseCase
+	health     *health.Health
+	grpc       *grpc.Server
+	// grpcServing is true while the gRPC server accepts connections.
+	grpcServing atomic.Bool
 }
 
 func main() {
 	var cfg config
 
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
+	flag.IntVar(&cfg.grpcPort, "grpc-port", 4001, "gRPC server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
 	flag.StringVar(&cfg.db.Dsn, "db-dsn", "", "PostgreSQL DSN")
 	flag.IntVar(&cfg.db.MaxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.MaxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.StringVar(&cfg.db.MaxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+	flag.StringVar(&cfg.store, "store", "postgres", "Storage backend (postgres|memory)")
+	flag.StringVar(&cfg.migrate, "migrate", "", "Run database migrations and exit (up|down)")
+	flag.Float64Var(&cfg.limiter.RPS, "limiter-rps", 2, "Rate limiter maximum requests per second per client")
+	flag.IntVar(&cfg.limiter.Burst, "limiter-burst", 4, "Rate limiter maximum burst per client")
+	flag.Float64Var(&cfg.limiter.GlobalRPS, "limiter-global-rps", 100, "Rate limiter maximum requests per second for all clients (0 disables)")
+	flag.IntVar(&cfg.limiter.GlobalBurst, "limiter-global-burst", 200, "Rate limiter maximum burst for all clients")
+	flag.BoolVar(&cfg.limiter.Enabled, "limiter-enabled", true, "Enable rate limiter")
+	flag.StringVar(&cfg.log.level, "log-level", "info", "Minimum log level (debug|info|warn|error|fatal|off)")
+	flag.BoolVar(&cfg.log.stackTraces, "log-stack-traces", false, "Add stack traces to error log entries")
+	flag.IntVar(&cfg.log.sampleFirst, "log-sample-first", 0, "Identical debug/info/warn entries logged per second before sampling (0 disables sampling)")
+	flag.IntVar(&cfg.log.sampleThereafter, "log-sample-thereafter", 100, "Log every nth identical entry once sampling kicks in")
+	flag.DurationVar(&cfg.drain, "drain-delay", 0, "Time /readyz reports failure before the server stops accepting requests on shutdown")
+	flag.StringVar(&cfg.metricsAddr, "metrics-addr", "localhost:4002", "Metrics server listen address, kept off the public API (empty disables)")
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "Time deleted contacts stay in the trash before they are purged (0 disables purging)")
+	flag.DurationVar(&cfg.trash.interval, "trash-purge-interval", time.Hour, "Interval between trash purges")
+	flag.StringVar(&cfg.outbox.publisher, "outbox-publisher", "bus", "Publisher of domain events (bus|file)")
+	flag.StringVar(&cfg.outbox.file, "outbox-file", "events.ndjson", "File the file publisher appends events to")
+	flag.DurationVar(&cfg.outbox.interval, "outbox-interval", time.Second, "Interval between outbox relays")
+	flag.IntVar(&cfg.outbox.batch, "outbox-batch", 100, "Maximum number of events published per relay")
+	flag.IntVar(&cfg.outbox.maxAttempts, "outbox-max-attempts", 10, "Attempts to publish an event before it is marked failed")
+	flag.DurationVar(&cfg.outbox.backoff, "outbox-backoff", time.Second, "Delay before an event is published again after a failure, doubled on every further failure")
+	flag.DurationVar(&cfg.outbox.maxBackoff, "outbox-max-backoff", 10*time.Minute, "Maximum delay between attempts to publish an event")
+	flag.IntVar(&cfg.webhook.workers, "webhook-workers", 4, "Number of webhook delivery workers")
+	flag.DurationVar(&cfg.webhook.pollInterval, "webhook-poll-interval", time.Second, "Interval between polls for due webhook deliveries")
+	flag.DurationVar(&cfg.webhook.timeout, "webhook-timeout", 10*time.Second, "Timeout of a webhook request")
+	flag.IntVar(&cfg.webhook.maxAttempts, "webhook-max-attempts", 8, "Attempts of a webhook delivery before it fails")
+	flag.DurationVar(&cfg.webhook.backoff, "webhook-backoff", 5*time.Second, "Delay before the first webhook retry, doubled on every further retry")
+	flag.DurationVar(&cfg.webhook.maxBackoff, "webhook-max-backoff", time.Hour, "Maximum delay between webhook retries")
+	flag.IntVar(&cfg.webhook.disableAfter, "webhook-disable-after", 5, "Failed deliveries in a row after which a webhook is disabled")
+	flag.StringVar(&cfg.phone.region, "phone-region", "US", "Region of phone numbers written without a country code")
+	flag.StringVar(&cfg.phone.regions, "phone-regions", strings.Join(phone.Regions(), ","), "Comma-separated regions whose numbering plans phone numbers are validated against")
 	flag.Parse()
 
-	db, err := postgres.OpenDB(cfg.db)
+	logLevel, err := jsonlog.ParseLevel(cfg.log.level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if cfg.trash.interval <= 0 {
+		fmt.Fprintln(os.Stderr, "trash-purge-interval must be positive")
+		os.Exit(2)
+	}
+
+	if cfg.outbox.interval <= 0 || cfg.outbox.batch <= 0 ||
+		cfg.outbox.maxAttempts <= 0 || cfg.outbox.maxAttempts > math.MaxInt32 ||
+		cfg.outbox.backoff <= 0 || cfg.outbox.maxBackoff < cfg.outbox.backoff {
+		fmt.Fprintln(os.Stderr, "outbox flags must be positive and outbox-max-backoff must not be less than outbox-backoff")
+		os.Exit(2)
+	}
+
+	if cfg.webhook.workers <= 0 || cfg.webhook.pollInterval <= 0 || cfg.webhook.timeout <= 0 ||
+		cfg.webhook.maxAttempts <= 0 || cfg.webhook.maxAttempts > math.MaxInt32 ||
+		cfg.webhook.backoff <= 0 || cfg.webhook.maxBackoff < cfg.webhook.backoff ||
+		cfg.webhook.disableAfter <= 0 || cfg.webhook.disableAfter > math.MaxInt32 {
+		fmt.Fprintln(os.Stderr, "webhook flags must be positive and webhook-max-backoff must not be less than webhook-backoff")
+		os.Exit(2)
+	}
+
+	phonePlans, err := phone.PlansFor(strings.Split(cfg.phone.regions, ","))
 	if err != nil {
-		fmt.Println("Error while opening DB " + err.Error())
-		return
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	phoneParser, err := phone.NewParser(strings.ToUpper(cfg.phone.region), phonePlans)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var logOptions []jsonlog.Option
+	if cfg.log.stackTraces {
+		logOptions = append(logOptions, jsonlog.WithStackTraces())
+	}
+	if cfg.log.sampleFirst > 0 {
+		logOptions = append(logOptions, jsonlog.WithSampling(time.Second, cfg.log.sampleFirst, cfg.log.sampleThereafter))
+	}
+
+	logger := jsonlog.New(os.Stdout, logLevel, logOptions...)
+
+	var (
+		db                   *sql.DB
+		contactRepository    domain.ContactRepository
+		groupRepository      domain.GroupRepository
+		userRepository       domain.UserRepository
+		tokenRepository      domain.TokenRepository
+		permissionRepository domain.PermissionRepository
+		historyRepository    domain.HistoryRepository
+		outboxRepository     domain.OutboxRepository
+		webhookRepository    domain.WebhookRepository
+	)
+
+	switch cfg.store {
+	case "postgres":
+		db, err = postgres.OpenDB(cfg.db)
+		if err != nil {
+			fmt.Println("Error while opening DB " + err.Error())
+			return
+		}
+
+		if cfg.migrate != "" {
+			err = migrate(db, logger, cfg.migrate)
+			if err != nil {
+				logger.PrintFatal(err, nil)
+			}
+			return
+		}
+
+		contactRepository = repository.NewContactRepository(db)
+		groupRepository = repository.NewGroupRepository(db)
+		userRepository = repository.NewUserRepository(db)
+		tokenRepository = repository.NewTokenRepository(db)
+		permissionRepository = repository.NewPermissionRepository(db)
+		historyRepository = repository.NewHistoryRepository(db)
+		outboxRepository = repository.NewOutboxRepository(db)
+		webhookRepository = repository.NewWebhookRepository(db)
+	case "memory":
+		if cfg.migrate != "" {
+			logger.PrintFatal(errors.New("migrations require -store=postgres"), nil)
+		}
+
+		memoryStore := repository.NewMemoryStore()
+		contactRepository = repository.NewMemoryContactRepository(memoryStore)
+		groupRepository = repository.NewMemoryGroupRepository(memoryStore)
+		userRepository = repository.NewMemoryUserRepository(memoryStore)
+		tokenRepository = repository.NewMemoryTokenRepository(memoryStore)
+		permissionRepository = repository.NewMemoryPermissionRepository(memoryStore)
+		historyRepository = repository.NewMemoryHistoryRepository(memoryStore)
+		outboxRepository = repository.NewMemoryOutboxRepository(memoryStore)
+		webhookRepository = repository.NewMemoryWebhookRepository(memoryStore)
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown store %q (expected postgres|memory)", cfg.store), nil)
 	}
 
-	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 	router := httprouter.New()
-	contactRepository := repository.NewContactRepository(db)
-	contactUseCase := useCase.NewContactUsecase(contactRepository, 6*time.Second)
-	delivery.NewContactHandler(router, logger, contactUseCase)
 
-	groupRepository := repository.NewGroupRepository(db)
+	registry := metrics.NewRegistry()
+	if db != nil {
+		postgres.RegisterStats(registry, db)
+	}
+
+	healthChecks := health.New()
+	if db != nil {
+		migrator, err := postgres.NewMigrator(db, migrations.FS)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		healthChecks.Register("database", 2*time.Second, postgres.PingCheck(db))
+		healthChecks.Register("migrations", 2*time.Second, postgres.MigrationCheck(migrator))
+	}
+	delivery.NewHealthHandler(router, logger, healthChecks, version, cfg.env)
+
+	userUseCase := useCase.NewUserUsecase(userRepository, tokenRepository, permissionRepository, 6*time.Second)
+	delivery.NewUserHandler(router, logger, userUseCase)
+	middleware := delivery.NewMiddleware(logger, userUseCase)
+
+	contactUseCase := useCase.NewContactUsecase(contactRepository, historyRepository, phoneParser, 6*time.Second)
+	delivery.NewContactHandler(router, logger, middleware, contactUseCase, phoneParser)
+
+	limiter := delivery.NewRateLimiter(logger, cfg.limiter)
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			middleware.UnaryRequestID,
+			middleware.UnaryRecoverPanic,
+			limiter.UnaryRateLimit,
+			middleware.UnaryAuthenticate,
+			limiter.UnaryRateLimitUser,
+		),
+		grpc.ChainStreamInterceptor(
+			middleware.StreamRequestID,
+			middleware.StreamRecoverPanic,
+			limiter.StreamRateLimit,
+			middleware.StreamAuthenticate,
+			limiter.StreamRateLimitUser,
+		),
+	)
+	delivery.NewGreetServer(grpcServer, logger)
+	delivery.NewContactGRPCServer(grpcServer, logger, contactUseCase, phoneParser)
+
 	groupUseCase := useCase.NewGroupUsecase(groupRepository, 6*time.Second)
-	delivery.NewGroupHandler(router, logger, groupUseCase)
+	delivery.NewGroupHandler(router, logger, middleware, groupUseCase, phoneParser)
+	delivery.NewAdminHandler(router, logger, middleware)
+
+	historyUseCase := useCase.NewHistoryUsecase(historyRepository, 6*time.Second)
+	delivery.NewHistoryHandler(router, logger, middleware, historyUseCase)
+
+	webhookPolicy := domain.WebhookPolicy{
+		Timeout:      cfg.webhook.timeout,
+		MaxAttempts:  int32(cfg.webhook.maxAttempts),
+		Backoff:      cfg.webhook.backoff,
+		MaxBackoff:   cfg.webhook.maxBackoff,
+		DisableAfter: int32(cfg.webhook.disableAfter),
+	}
+	webhookUseCase := useCase.NewWebhookUsecase(webhookRepository, publisher.NewWebhookSender(version), webhookPolicy, 6*time.Second)
+	delivery.NewWebhookHandler(router, logger, middleware, webhookUseCase)
+
+	// Events are relayed to the bus, which hands them to the configured
+	// publisher and to the webhooks.
+	bus := publisher.NewBus()
+	switch cfg.outbox.publisher {
+	case "bus":
+		bus.Subscribe(func(ctx context.Context, event *domain.Event) error {
+			logger.Debug("published event", jsonlog.Int64("event_id", event.ID), jsonlog.String("event_type", string(event.Type)))
+			return nil
+		})
+	case "file":
+		file, err := publisher.NewFile(cfg.outbox.file)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		defer file.Close()
+		bus.Subscribe(file.Publish)
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown outbox publisher %q (expected bus|file)", cfg.outbox.publisher), nil)
+	}
+	bus.Subscribe(webhookUseCase.Enqueue)
+	outboxPolicy := domain.OutboxPolicy{
+		MaxAttempts: int32(cfg.outbox.maxAttempts),
+		Backoff:     cfg.outbox.backoff,
+		MaxBackoff:  cfg.outbox.maxBackoff,
+	}
+	outboxUseCase := useCase.NewOutboxUsecase(outboxRepository, bus, outboxPolicy, 6*time.Second)
 
 	service := &service{
-		config: cfg,
-		db:     db,
-		logger: logger,
-		router: router,
+		config:     cfg,
+		db:         db,
+		logger:     logger,
+		router:     router,
+		middleware: middleware,
+		limiter:    limiter,
+		metrics:    delivery.NewMetrics(registry, router),
+		registry:   registry,
+		contacts:   contactUseCase,
+		outbox:     outboxUseCase,
+		webhooks:   webhookUseCase,
+		health:     healthChecks,
+		grpc:       grpcServer,
+		wg:         &sync.WaitGroup{},
 		// mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
 	}
 
+	healthChecks.Register("grpc", time.Second, func(ctx context.Context) error {
+		if !service.grpcServing.Load() {
+			return errors.New("grpc server is not serving")
+		}
+		return nil
+	})
+
 	err = service.serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/store/postgres"
+	"advanced.microservices/services/contact/migrations"
+)
+
+// migrate applies the embedded schema migrations according to the -migrate
+// flag: "up" applies every pending migration, "down" rolls back the latest one.
+func migrate(db *sql.DB, logger *jsonlog.Logger, direction string) error {
+	migrator, err := postgres.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var version int64
+
+	switch direction {
+	case "up":
+		version, err = migrator.Up(ctx)
+	case "down":
+		version, err = migrator.Down(ctx, 1)
+	default:
+		return fmt.Errorf("unknown migration direction %q (expected up|down)", direction)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.PrintInfo("database migrations applied", map[string]string{
+		"direction": direction,
+		"version":   strconv.FormatInt(version, 10),
+	})
+	return nil
+}
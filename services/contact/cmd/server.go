@@ -4,23 +4,104 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"advanced.microservices/services/contact/internal/delivery"
 )
 
 func (service *service) serve() error {
+	// Request contexts derive from requests, which is canceled once the
+	// shutdown deadline passes so that the handlers still running give up
+	// their queries as they do when a client goes away.
+	requests, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", service.config.port),
-		Handler:      service.router,
+		Handler:      service.routes(),
+		BaseContext:  func(net.Listener) context.Context { return requests },
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
 
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", service.config.grpcPort))
+	if err != nil {
+		return err
+	}
+
+	var metricsServer *http.Server
+	if service.config.metricsAddr != "" {
+		metricsListener, err := net.Listen("tcp", service.config.metricsAddr)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", service.registry.Handler())
+		metricsServer = &http.Server{
+			Addr:         service.config.metricsAddr,
+			Handler:      mux,
+			IdleTimeout:  time.Minute,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+		go func() {
+			service.logger.PrintInfo("starting metrics server", map[string]string{
+				"addr": metricsListener.Addr().String(),
+			})
+			err := metricsServer.Serve(metricsListener)
+			if !errors.Is(err, http.ErrServerClosed) {
+				service.logger.PrintError(err, nil)
+			}
+		}()
+	}
+
 	shutdownError := make(chan error)
+	grpcError := make(chan error, 1)
+
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	if service.config.limiter.Enabled {
+		service.wg.Add(1)
+		go func() {
+			defer service.wg.Done()
+			service.limiter.EvictStale(background)
+		}()
+	}
+
+	service.wg.Add(1)
+	go func() {
+		defer service.wg.Done()
+		service.relayOutbox(background)
+	}()
+
+	service.wg.Add(1)
+	go func() {
+		defer service.wg.Done()
+		service.backfillPhoneE164(background)
+	}()
+
+	for i := 0; i < service.config.webhook.workers; i++ {
+		service.wg.Add(1)
+		go func() {
+			defer service.wg.Done()
+			service.deliverWebhooks(background)
+		}()
+	}
+
+	if service.config.trash.retention > 0 {
+		service.wg.Add(1)
+		go func() {
+			defer service.wg.Done()
+			service.purgeTrash(background)
+		}()
+	}
 
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -29,17 +110,43 @@ func (service *service) serve() error {
 		service.logger.PrintInfo("caught signal", map[string]string{
 			"signal": s.String(),
 		})
+
+		// Fail readiness first so that load balancers stop sending traffic
+		// before the listener closes.
+		service.health.Drain()
+		time.Sleep(service.config.drain)
+
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 		err := server.Shutdown(ctx)
-		if err != nil {
-			shutdownError <- err
+		if errors.Is(err, context.DeadlineExceeded) {
+			cancelRequests()
 		}
+		service.stopGRPC(ctx)
+		if metricsServer != nil {
+			err := metricsServer.Shutdown(ctx)
+			if err != nil {
+				service.logger.PrintError(err, map[string]string{
+					"addr": metricsServer.Addr,
+				})
+			}
+		}
+		stopBackground()
 		service.logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": server.Addr,
 		})
 		service.wg.Wait()
-		shutdownError <- nil
+		shutdownError <- err
+	}()
+
+	go func() {
+		service.logger.PrintInfo("starting grpc server", map[string]string{
+			"addr": listener.Addr().String(),
+		})
+		service.grpcServing.Store(true)
+		err := service.grpc.Serve(listener)
+		service.grpcServing.Store(false)
+		grpcError <- err
 	}()
 
 	service.logger.PrintInfo("starting server", map[string]string{
@@ -47,8 +154,9 @@ func (service *service) serve() error {
 		"env":  service.config.env,
 	})
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
+		service.grpc.Stop()
 		return err
 	}
 
@@ -57,8 +165,60 @@ func (service *service) serve() error {
 		return err
 	}
 
+	err = <-grpcError
+	if err != nil {
+		return err
+	}
+
 	service.logger.PrintInfo("stopped server", map[string]string{
 		"addr": server.Addr,
 	})
 	return nil
 }
+
+// stopGRPC drains in-flight RPCs, falling back to a hard stop once ctx
+// expires so that long-lived streams cannot block the shutdown forever.
+func (service *service) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		service.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		service.grpc.Stop()
+	}
+}
+
+// routes wraps the router in the middleware shared by every HTTP endpoint.
+// The health probes skip the rate limiter and authentication: probes all come
+// from the few addresses of the kubelet or load balancer, and a 429 would be
+// read as an unhealthy instance.
+func (service *service) routes() http.Handler {
+	probes := delivery.Chain(service.router,
+		service.middleware.RequestID,
+		service.middleware.LogRequests,
+		service.metrics.Instrument,
+		service.middleware.RecoverPanic,
+	)
+	api := delivery.Chain(service.router,
+		service.middleware.RequestID,
+		service.middleware.LogRequests,
+		service.metrics.Instrument,
+		service.middleware.RecoverPanic,
+		service.limiter.RateLimit,
+		service.middleware.Authenticate,
+		service.limiter.RateLimitUser,
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/livez", "/readyz":
+			probes.ServeHTTP(w, r)
+		default:
+			api.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package delivery
+
+import (
+	"net/http"
+
+	"advanced.microservices/pkg/helpers"
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/services/contact/internal/domain"
+	"github.com/julienschmidt/httprouter"
+)
+
+type AdminHandler struct {
+	logger   *jsonlog.Logger
+	response responseHandler
+}
+
+func NewAdminHandler(router *httprouter.Router, logger *jsonlog.Logger, middleware *Middleware) {
+	handler := &AdminHandler{
+		logger:   logger,
+		response: responseHandler{logger: logger},
+	}
+	router.HandlerFunc(http.MethodGet, "/admin/log-level", middleware.requirePermission(domain.PermissionLogsAdmin, handler.getLogLevel))
+	router.HandlerFunc(http.MethodPut, "/admin/log-level", middleware.requirePermission(domain.PermissionLogsAdmin, handler.setLogLevel))
+}
+
+func (handler *AdminHandler) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	err := writeJSON(w, http.StatusOK, envelope{"level": handler.logger.Level().String()}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+// setLogLevel changes the level of every logger of the process until the next
+// restart.
+func (handler *AdminHandler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Level string `json:"level"`
+	}
+
+	err := helpers.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	level, err := jsonlog.ParseLevel(input.Level)
+
+	v := validator.New()
+
+	if v.Check(err == nil, "level", "must be one of debug, info, warn, error, fatal or off"); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	previous := handler.logger.Level()
+	handler.logger.SetLevel(level)
+
+	handler.logger.Warn("log level changed",
+		jsonlog.String("from", previous.String()),
+		jsonlog.String("to", level.String()),
+		jsonlog.Int64("user_id", contextGetUser(r).ID),
+	)
+
+	err = writeJSON(w, http.StatusOK, envelope{"level": level.String()}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,167 @@
+package delivery
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"advanced.microservices/pkg/jsonlog"
+)
+
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients so
+// that they cannot flood the logs.
+const maxRequestIDLength = 128
+
+// Chain wraps handler in the given middleware. The first middleware is the
+// outermost one and sees the request first.
+func Chain(handler http.Handler, middleware ...func(http.Handler) http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// RecoverPanic turns a panic in a handler into a 500 response and closes the
+// connection instead of letting net/http drop it silently.
+func (middleware *Middleware) RecoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				w.Header().Set("Connection", "close")
+				middleware.response.serverErrorResponse(w, r, fmt.Errorf("%v", err))
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestID reuses the X-Request-ID sent by the client, or generates one, and
+// echoes it in the response so that both sides can correlate logs. The ID and
+// the trace ID of a W3C traceparent header are added to the log fields of the
+// request context, which every later middleware and handler logs through.
+func (middleware *Middleware) RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+
+		if !validRequestID(requestID) {
+			var err error
+			requestID, err = generateRequestID()
+			if err != nil {
+				middleware.response.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+
+		fields := []jsonlog.Field{jsonlog.String(jsonlog.KeyRequestID, requestID)}
+		if traceID, ok := parseTraceParent(r.Header.Get("traceparent")); ok {
+			fields = append(fields, jsonlog.String(jsonlog.KeyTraceID, traceID))
+		}
+		r = r.WithContext(jsonlog.NewContext(r.Context(), fields...))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LogRequests writes one access log entry per request once the response has
+// been sent.
+func (middleware *Middleware) LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		middleware.response.logger.Ctx(r.Context()).Info("request completed",
+			jsonlog.String("request_method", r.Method),
+			jsonlog.String("request_path", r.URL.Path),
+			jsonlog.String("remote_addr", r.RemoteAddr),
+			jsonlog.Int("status", recorder.status),
+			jsonlog.Int64("bytes", recorder.bytes),
+			jsonlog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTraceParent returns the trace ID of a W3C Trace Context traceparent
+// header, formatted as version-traceid-parentid-flags.
+func parseTraceParent(header string) (string, bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 {
+		return "", false
+	}
+
+	traceID := parts[1]
+	if traceID == strings.Repeat("0", 32) {
+		return "", false
+	}
+	for i := 0; i < len(traceID); i++ {
+		if !strings.ContainsRune("0123456789abcdef", rune(traceID[i])) {
+			return "", false
+		}
+	}
+	return traceID, true
+}
+
+func generateRequestID() (string, error) {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// responseRecorder captures the status code and body size of a response. It
+// forwards Flush so that streamed exports keep working behind it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(b []byte) (int, error) {
+	recorder.wroteHeader = true
+	n, err := recorder.ResponseWriter.Write(b)
+	recorder.bytes += int64(n)
+	return n, err
+}
+
+func (recorder *responseRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"advanced.microservices/pkg/helpers"
 	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/phone"
 	"advanced.microservices/pkg/validator"
 	"advanced.microservices/services/contact/internal/domain"
 	"advanced.microservices/services/contact/internal/repository"
@@ -15,23 +18,29 @@ import (
 
 type ContactHandler struct {
 	contactUseCase domain.ContactUseCase
+	phones         *phone.Parser
 	response       responseHandler
 }
 
-func NewContactHandler(router *httprouter.Router, logger *jsonlog.Logger, contactUseCase domain.ContactUseCase) {
+func NewContactHandler(router *httprouter.Router, logger *jsonlog.Logger, middleware *Middleware, contactUseCase domain.ContactUseCase, phones *phone.Parser) {
 	handler := &ContactHandler{
 		contactUseCase: contactUseCase,
+		phones:         phones,
 		response:       responseHandler{logger: logger},
 	}
-	router.HandlerFunc(http.MethodGet, "/contact", handler.getById)
-	router.HandlerFunc(http.MethodPost, "/contact", handler.create)
-	router.HandlerFunc(http.MethodDelete, "/contact", handler.delete)
-	router.HandlerFunc(http.MethodPut, "/contact", handler.update)
-	router.HandlerFunc(http.MethodGet, "/contact/healthcheck", handler.healthcheck)
-}
-
-func (handler *ContactHandler) healthcheck(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, envelope{"status": "ok"}, nil)
+	router.HandlerFunc(http.MethodGet, "/contacts/:id", middleware.requirePermission(domain.PermissionContactsRead, handler.getById))
+	router.HandlerFunc(http.MethodPost, "/contacts", middleware.requirePermission(domain.PermissionContactsWrite, handler.create))
+	router.HandlerFunc(http.MethodDelete, "/contacts/:id", middleware.requirePermission(domain.PermissionContactsWrite, handler.delete))
+	router.HandlerFunc(http.MethodPut, "/contacts/:id", middleware.requirePermission(domain.PermissionContactsWrite, handler.update))
+	router.HandlerFunc(http.MethodPost, "/contacts-import", middleware.requirePermission(domain.PermissionContactsWrite, handler.importContacts))
+	router.HandlerFunc(http.MethodGet, "/contacts-export", middleware.requirePermission(domain.PermissionContactsRead, handler.export))
+	router.HandlerFunc(http.MethodGet, "/contacts", middleware.requirePermission(domain.PermissionContactsRead, handler.list))
+	router.HandlerFunc(http.MethodGet, "/contacts/:id/groups", middleware.requirePermission(domain.PermissionContactsRead, handler.listGroups))
+	router.HandlerFunc(http.MethodGet, "/trash/contacts", middleware.requirePermission(domain.PermissionContactsRead, handler.listDeleted))
+	router.HandlerFunc(http.MethodPost, "/contacts/:id/restore", middleware.requirePermission(domain.PermissionContactsWrite, handler.restore))
+	router.HandlerFunc(http.MethodPost, "/contacts/:id/revert", middleware.requirePermission(domain.PermissionContactsWrite, handler.revert))
+	router.HandlerFunc(http.MethodGet, "/contacts-duplicates", middleware.requirePermission(domain.PermissionContactsRead, handler.findDuplicates))
+	router.HandlerFunc(http.MethodPost, "/contacts/:id/merge", middleware.requirePermission(domain.PermissionContactsWrite, handler.merge))
 }
 
 func (handler *ContactHandler) getById(w http.ResponseWriter, r *http.Request) {
@@ -41,7 +50,16 @@ func (handler *ContactHandler) getById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	contact, err := handler.contactUseCase.GetByID(id)
+	v := validator.New()
+
+	region := readRegion(r.URL.Query(), handler.phones, v)
+
+	if !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contact, err := handler.contactUseCase.GetByID(r.Context(), id, contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrRecordNotFound):
@@ -52,6 +70,8 @@ func (handler *ContactHandler) getById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	domain.FormatPhones(contact, handler.phones, region)
+
 	err = writeJSON(w, http.StatusOK, envelope{"contact": contact}, nil)
 	if err != nil {
 		handler.response.serverErrorResponse(w, r, err)
@@ -60,8 +80,16 @@ func (handler *ContactHandler) getById(w http.ResponseWriter, r *http.Request) {
 
 func (handler *ContactHandler) create(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		FullName string `json:"full_name"`
-		Phone    string `json:"phone"`
+		FullName     string               `json:"full_name"`
+		Phone        string               `json:"phone"`
+		Phones       []domain.PhoneNumber `json:"phones"`
+		Emails       []domain.Email       `json:"emails"`
+		Addresses    []domain.Address     `json:"addresses"`
+		Company      string               `json:"company"`
+		Title        string               `json:"title"`
+		Birthday     *domain.Date         `json:"birthday"`
+		Notes        string               `json:"notes"`
+		CustomFields map[string]string    `json:"custom_fields"`
 	}
 
 	err := helpers.ReadJSON(w, r, &input)
@@ -72,23 +100,39 @@ func (handler *ContactHandler) create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	contact := &domain.Contact{
-		FullName: input.FullName,
-		Phone:    input.Phone,
+		OwnerID:      contextGetUser(r).ID,
+		FullName:     input.FullName,
+		Phone:        input.Phone,
+		Phones:       input.Phones,
+		Emails:       input.Emails,
+		Addresses:    input.Addresses,
+		Company:      input.Company,
+		Title:        input.Title,
+		Birthday:     input.Birthday,
+		Notes:        input.Notes,
+		CustomFields: input.CustomFields,
 	}
 
+	domain.NormalizePhones(contact, handler.phones)
+
 	v := validator.New()
 
+	region := readRegion(r.URL.Query(), handler.phones, v)
+
 	if domain.ValidateContact(v, contact); !v.Valid() {
 		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
-	err = handler.contactUseCase.Create(contact)
+	err = handler.contactUseCase.Create(r.Context(), contact)
 
 	if err != nil {
 		handler.response.serverErrorResponse(w, r, err)
 		return
 	}
 
+	domain.FormatPhones(contact, handler.phones, region)
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/contacts/%d", contact.ID))
 
@@ -106,7 +150,7 @@ func (handler *ContactHandler) delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = handler.contactUseCase.Delete(id)
+	err = handler.contactUseCase.Delete(r.Context(), id, contextGetUser(r).ID)
 
 	if err != nil {
 		switch {
@@ -115,9 +159,10 @@ func (handler *ContactHandler) delete(w http.ResponseWriter, r *http.Request) {
 		default:
 			handler.response.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 
-	err = writeJSON(w, http.StatusOK, envelope{"message": "contact successfully deleted"}, nil)
+	err = writeJSON(w, http.StatusOK, envelope{"message": "contact moved to trash"}, nil)
 	if err != nil {
 		handler.response.serverErrorResponse(w, r, err)
 	}
@@ -130,7 +175,7 @@ func (handler *ContactHandler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	contact, err := handler.contactUseCase.GetByID(id)
+	contact, err := handler.contactUseCase.GetByID(r.Context(), id, contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrRecordNotFound):
@@ -141,9 +186,19 @@ func (handler *ContactHandler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Lists are replaced as a whole. Custom fields are merged: a null value
+	// removes the field. A null birthday clears it.
 	var input struct {
-		FullName *string `json:"full_name"`
-		Phone    *string `json:"phone"`
+		FullName     *string               `json:"full_name"`
+		Phone        *string               `json:"phone"`
+		Phones       *[]domain.PhoneNumber `json:"phones"`
+		Emails       *[]domain.Email       `json:"emails"`
+		Addresses    *[]domain.Address     `json:"addresses"`
+		Company      *string               `json:"company"`
+		Title        *string               `json:"title"`
+		Birthday     nullableDate          `json:"birthday"`
+		Notes        *string               `json:"notes"`
+		CustomFields map[string]*string    `json:"custom_fields"`
 	}
 
 	err = helpers.ReadJSON(w, r, &input)
@@ -160,14 +215,58 @@ func (handler *ContactHandler) update(w http.ResponseWriter, r *http.Request) {
 	if input.Phone != nil {
 		contact.Phone = *input.Phone
 	}
+
+	if input.Phones != nil {
+		contact.Phones = *input.Phones
+	}
+
+	if input.Emails != nil {
+		contact.Emails = *input.Emails
+	}
+
+	if input.Addresses != nil {
+		contact.Addresses = *input.Addresses
+	}
+
+	if input.Company != nil {
+		contact.Company = *input.Company
+	}
+
+	if input.Title != nil {
+		contact.Title = *input.Title
+	}
+
+	if input.Birthday.Set {
+		contact.Birthday = input.Birthday.Date
+	}
+
+	if input.Notes != nil {
+		contact.Notes = *input.Notes
+	}
+
+	for key, value := range input.CustomFields {
+		if value == nil {
+			delete(contact.CustomFields, key)
+			continue
+		}
+		if contact.CustomFields == nil {
+			contact.CustomFields = make(map[string]string)
+		}
+		contact.CustomFields[key] = *value
+	}
+
+	domain.NormalizePhones(contact, handler.phones)
+
 	v := validator.New()
 
+	region := readRegion(r.URL.Query(), handler.phones, v)
+
 	if domain.ValidateContact(v, contact); !v.Valid() {
 		handler.response.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = handler.contactUseCase.Update(contact)
+	err = handler.contactUseCase.Update(r.Context(), contact)
 
 	if err != nil {
 		switch {
@@ -176,8 +275,11 @@ func (handler *ContactHandler) update(w http.ResponseWriter, r *http.Request) {
 		default:
 			handler.response.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 
+	domain.FormatPhones(contact, handler.phones, region)
+
 	err = writeJSON(w, http.StatusOK, envelope{"contact": contact}, nil)
 
 	if err != nil {
@@ -185,3 +287,301 @@ func (handler *ContactHandler) update(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+func (handler *ContactHandler) listGroups(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	groups, err := handler.contactUseCase.ListGroups(r.Context(), id, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"groups": groups}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *ContactHandler) list(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		FullName string
+		Phone    string
+		domain.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.FullName = helpers.ReadString(qs, "full_name", "")
+	input.Phone = helpers.ReadString(qs, "phone", "")
+	region := readRegion(qs, handler.phones, v)
+
+	input.Filters.Page = helpers.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = helpers.ReadInt(qs, "page_size", 20, v)
+	input.Filters.Sort = helpers.ReadString(qs, "sort", "id")
+	input.Filters.SortSafelist = domain.ContactSortSafelist
+
+	if domain.ValidateFilters(v, input.Filters); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contacts, metadata, err := handler.contactUseCase.List(r.Context(), contextGetUser(r).ID, input.FullName, input.Phone, input.Filters)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, contact := range contacts {
+		domain.FormatPhones(contact, handler.phones, region)
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"contacts": contacts, "metadata": metadata}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *ContactHandler) listDeleted(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		domain.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = helpers.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = helpers.ReadInt(qs, "page_size", 20, v)
+	input.Filters.Sort = helpers.ReadString(qs, "sort", "-deleted_at")
+	input.Filters.SortSafelist = domain.TrashSortSafelist
+
+	region := readRegion(qs, handler.phones, v)
+
+	if domain.ValidateFilters(v, input.Filters); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contacts, metadata, err := handler.contactUseCase.ListDeleted(r.Context(), contextGetUser(r).ID, input.Filters)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, contact := range contacts {
+		domain.FormatPhones(contact, handler.phones, region)
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"contacts": contacts, "metadata": metadata}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *ContactHandler) restore(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	region := readRegion(r.URL.Query(), handler.phones, v)
+
+	if !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contact, err := handler.contactUseCase.Restore(r.Context(), id, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	domain.FormatPhones(contact, handler.phones, region)
+
+	err = writeJSON(w, http.StatusOK, envelope{"contact": contact}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *ContactHandler) revert(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Version int32 `json:"version"`
+	}
+
+	err = helpers.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	region := readRegion(r.URL.Query(), handler.phones, v)
+
+	if v.Check(input.Version > 0, "version", "must be a positive integer"); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contact, err := handler.contactUseCase.Revert(r.Context(), id, contextGetUser(r).ID, input.Version)
+	if err != nil {
+		var validationErr *domain.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			handler.response.failedValidationResponse(w, r, validationErr.Errors)
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		case errors.Is(err, repository.ErrEditConflict):
+			handler.response.editConflictResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	domain.FormatPhones(contact, handler.phones, region)
+
+	err = writeJSON(w, http.StatusOK, envelope{"contact": contact}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+// findDuplicates serves the clusters of likely duplicate contacts. Names
+// match from the similarity given by the threshold query parameter, between 0
+// and 1.
+func (handler *ContactHandler) findDuplicates(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	threshold := helpers.ReadFloat(qs, "threshold", domain.DefaultDuplicateThreshold, v)
+	region := readRegion(qs, handler.phones, v)
+
+	if v.Check(threshold > 0 && threshold <= 1, "threshold", "must be greater than 0 and at most 1"); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	clusters, err := handler.contactUseCase.FindDuplicates(r.Context(), contextGetUser(r).ID, threshold)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, cluster := range clusters {
+		for _, contact := range cluster.Contacts {
+			domain.FormatPhones(contact, handler.phones, region)
+		}
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"clusters": clusters}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+// merge folds the contact given by duplicate_id into the one in the path,
+// which is kept, and moves the duplicate to the trash.
+func (handler *ContactHandler) merge(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		DuplicateID int64 `json:"duplicate_id"`
+	}
+
+	err = helpers.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.DuplicateID > 0, "duplicate_id", "must be a positive integer")
+	v.Check(input.DuplicateID != id, "duplicate_id", "must not be the contact itself")
+
+	region := readRegion(r.URL.Query(), handler.phones, v)
+
+	if !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contact, err := handler.contactUseCase.Merge(r.Context(), id, input.DuplicateID, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	domain.FormatPhones(contact, handler.phones, region)
+
+	err = writeJSON(w, http.StatusOK, envelope{"contact": contact}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+// readRegion reads the region query parameter: the region, such as "GB",
+// whose layout the phone numbers of the response are shown in. It defaults to
+// the default region of phones.
+func readRegion(qs url.Values, phones *phone.Parser, v *validator.Validator) string {
+	region := strings.ToUpper(helpers.ReadString(qs, "region", phones.DefaultRegion()))
+	v.Check(phones.HasRegion(region), "region", "must be a supported region")
+	return region
+}
+
+// nullableDate tells a null date, which clears it, from a missing one, which
+// leaves it unchanged.
+type nullableDate struct {
+	Set  bool
+	Date *domain.Date
+}
+
+func (date *nullableDate) UnmarshalJSON(data []byte) error {
+	date.Set = true
+	if string(data) == "null" {
+		date.Date = nil
+		return nil
+	}
+
+	date.Date = new(domain.Date)
+	return date.Date.UnmarshalJSON(data)
+}
@@ -0,0 +1,37 @@
+package delivery
+
+import (
+	"context"
+	"net/http"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+// contextSetUser also records authenticated users as the actor of the changes
+// made by the request.
+func contextSetUser(r *http.Request, user *domain.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	if !user.IsAnonymous() {
+		ctx = domain.NewActorContext(ctx, user.ID)
+	}
+	return r.WithContext(ctx)
+}
+
+// contextGetUser is only called where the Authenticate middleware has run, so
+// a missing user is a programming error.
+func contextGetUser(r *http.Request) *domain.User {
+	user, ok := userFromContext(r.Context())
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return user
+}
+
+func userFromContext(ctx context.Context) (*domain.User, bool) {
+	user, ok := ctx.Value(userContextKey).(*domain.User)
+	return user, ok
+}
@@ -0,0 +1,385 @@
+package delivery
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced.microservices/pkg/helpers"
+	"advanced.microservices/pkg/phone"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/pkg/vcard"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/repository"
+)
+
+// exportFlushEvery is the number of records written between two flushes of
+// the response, so that clients start receiving data right away.
+const exportFlushEvery = 100
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"vcard":  "text/vcard; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+}
+
+var exportExtensions = map[string]string{
+	"csv":    "csv",
+	"vcard":  "vcf",
+	"ndjson": "ndjson",
+}
+
+type exportEncoder[T any] interface {
+	Encode(value *T) error
+	Flush() error
+}
+
+func (handler *ContactHandler) export(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	format := helpers.ReadString(qs, "format", "csv")
+	version := helpers.ReadString(qs, "version", "3.0")
+	groupID := helpers.ReadInt(qs, "group_id", 0, v)
+	region := readRegion(qs, handler.phones, v)
+
+	v.Check(validator.PermittedValue(format, "csv", "vcard", "ndjson"), "format", "must be one of csv, vcard or ndjson")
+	v.Check(validator.PermittedValue(version, "3.0", "4.0"), "version", "must be 3.0 or 4.0")
+	v.Check(groupID >= 0, "group_id", "must be a positive integer")
+
+	if !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	cursor, err := handler.contactUseCase.Export(r.Context(), contextGetUser(r).ID, int64(groupID))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer cursor.Close()
+
+	filename := "contacts"
+	if groupID > 0 {
+		filename = fmt.Sprintf("contacts-group-%d", groupID)
+	}
+
+	out := startDownload(w, format, filename)
+
+	var encoder exportEncoder[domain.Contact]
+	switch format {
+	case "csv":
+		encoder = newCSVEncoder(out, contactCSVHeader, contactCSVRecord)
+	case "vcard":
+		encoder = &vcardContactEncoder{encoder: vcard.NewEncoder(out, version), version: version}
+	case "ndjson":
+		encoder = newNDJSONEncoder[domain.Contact](out)
+	}
+
+	encoder = &phoneFormatEncoder{exportEncoder: encoder, phones: handler.phones, region: region}
+
+	err = streamExport[domain.Contact](w, out, cursor, encoder)
+	if err != nil {
+		handler.response.logError(r, err)
+	}
+}
+
+func (handler *GroupHandler) export(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	format := helpers.ReadString(r.URL.Query(), "format", "csv")
+
+	if v.Check(validator.PermittedValue(format, "csv", "ndjson"), "format", "must be one of csv or ndjson"); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	cursor, err := handler.groupUseCase.Export(r.Context(), contextGetUser(r).ID)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+	defer cursor.Close()
+
+	out := startDownload(w, format, "groups")
+
+	var encoder exportEncoder[domain.Group]
+	switch format {
+	case "csv":
+		encoder = newCSVEncoder(out, []string{"id", "parent_id", "group_name", "created_at", "version"}, groupCSVRecord)
+	case "ndjson":
+		encoder = newNDJSONEncoder[domain.Group](out)
+	}
+
+	err = streamExport[domain.Group](w, out, cursor, encoder)
+	if err != nil {
+		handler.response.logError(r, err)
+	}
+}
+
+// startDownload sends the download headers and returns a buffered writer
+// over the response body.
+func startDownload(w http.ResponseWriter, format string, filename string) *bufio.Writer {
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, exportExtensions[format]))
+	w.WriteHeader(http.StatusOK)
+	return bufio.NewWriter(w)
+}
+
+// streamExport copies every record of the cursor to the encoder. Once the
+// headers are sent an error can no longer be reported to the client, so it is
+// returned for logging and the truncated body signals the failure.
+func streamExport[T any](w http.ResponseWriter, out *bufio.Writer, cursor domain.Cursor[T], encoder exportEncoder[T]) error {
+	flusher, _ := w.(http.Flusher)
+
+	for n := 1; cursor.Next(); n++ {
+		if err := encoder.Encode(cursor.Value()); err != nil {
+			return err
+		}
+
+		if n%exportFlushEvery == 0 {
+			if err := encoder.Flush(); err != nil {
+				return err
+			}
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// phoneFormatEncoder lays out the phone numbers of each contact for region
+// before handing it to the wrapped encoder.
+type phoneFormatEncoder struct {
+	exportEncoder[domain.Contact]
+	phones *phone.Parser
+	region string
+}
+
+func (encoder *phoneFormatEncoder) Encode(contact *domain.Contact) error {
+	domain.FormatPhones(contact, encoder.phones, encoder.region)
+	return encoder.exportEncoder.Encode(contact)
+}
+
+type csvEncoder[T any] struct {
+	writer *csv.Writer
+	header []string
+	record func(value *T) []string
+}
+
+func newCSVEncoder[T any](out io.Writer, header []string, record func(value *T) []string) *csvEncoder[T] {
+	return &csvEncoder[T]{writer: csv.NewWriter(out), header: header, record: record}
+}
+
+func (encoder *csvEncoder[T]) Encode(value *T) error {
+	if encoder.header != nil {
+		if err := encoder.writer.Write(encoder.header); err != nil {
+			return err
+		}
+		encoder.header = nil
+	}
+	return encoder.writer.Write(encoder.record(value))
+}
+
+func (encoder *csvEncoder[T]) Flush() error {
+	if encoder.header != nil {
+		if err := encoder.writer.Write(encoder.header); err != nil {
+			return err
+		}
+		encoder.header = nil
+	}
+	encoder.writer.Flush()
+	return encoder.writer.Error()
+}
+
+// contactCSVHeader lists the columns of a contact CSV export. Phones,
+// emails, addresses and custom fields hold JSON, in the shape of the API, so
+// that readCSVContacts can read them back.
+var contactCSVHeader = []string{"id", "full_name", "phone", "phone_e164", "phones", "emails", "addresses", "company", "title", "birthday", "notes", "custom_fields", "created_at", "version"}
+
+func contactCSVRecord(contact *domain.Contact) []string {
+	birthday := ""
+	if contact.Birthday != nil {
+		birthday = contact.Birthday.String()
+	}
+
+	return []string{
+		strconv.FormatInt(contact.ID, 10),
+		contact.FullName,
+		contact.Phone,
+		contact.PhoneE164,
+		jsonCell(contact.Phones, len(contact.Phones)),
+		jsonCell(contact.Emails, len(contact.Emails)),
+		jsonCell(contact.Addresses, len(contact.Addresses)),
+		contact.Company,
+		contact.Title,
+		birthday,
+		contact.Notes,
+		jsonCell(contact.CustomFields, len(contact.CustomFields)),
+		contact.CreatedAt.Format(time.RFC3339),
+		strconv.FormatInt(int64(contact.Version), 10),
+	}
+}
+
+// jsonCell encodes value as JSON, or returns an empty cell when value holds
+// no elements.
+func jsonCell(value any, length int) string {
+	if length == 0 {
+		return ""
+	}
+
+	js, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(js)
+}
+
+func groupCSVRecord(group *domain.Group) []string {
+	parentID := ""
+	if group.ParentID != nil {
+		parentID = strconv.FormatInt(*group.ParentID, 10)
+	}
+
+	return []string{
+		strconv.FormatInt(group.ID, 10),
+		parentID,
+		group.GroupName,
+		group.CreatedAt.Format(time.RFC3339),
+		strconv.FormatInt(int64(group.Version), 10),
+	}
+}
+
+type ndjsonEncoder[T any] struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONEncoder[T any](out io.Writer) *ndjsonEncoder[T] {
+	return &ndjsonEncoder[T]{encoder: json.NewEncoder(out)}
+}
+
+func (encoder *ndjsonEncoder[T]) Encode(value *T) error {
+	return encoder.encoder.Encode(value)
+}
+
+func (encoder *ndjsonEncoder[T]) Flush() error {
+	return nil
+}
+
+type vcardContactEncoder struct {
+	encoder *vcard.Encoder
+	version string
+}
+
+func (encoder *vcardContactEncoder) Encode(contact *domain.Contact) error {
+	return encoder.encoder.Encode(contactToVCard(contact, encoder.version))
+}
+
+func (encoder *vcardContactEncoder) Flush() error {
+	return nil
+}
+
+// contactToVCard is the inverse of contactFromVCard: the words of the full
+// name map onto the family, given and additional components of N. Custom
+// fields have no vCard counterpart and are left out.
+func contactToVCard(contact *domain.Contact, version string) vcard.Card {
+	var card vcard.Card
+
+	parts := strings.Fields(contact.FullName)
+	components := make([]string, 5)
+	for i := 0; i < len(parts); i++ {
+		switch {
+		case i < 2:
+			components[i] = parts[i]
+		case components[2] == "":
+			components[2] = parts[i]
+		default:
+			components[2] += " " + parts[i]
+		}
+	}
+
+	card.Add("UID", fmt.Sprintf("urn:contact:%d", contact.ID), nil)
+	card.Add("FN", contact.FullName, nil)
+	card.AddProperty("N", vcard.Structured(components...))
+	if contact.Phone != "" {
+		card.Add("TEL", contact.Phone, map[string][]string{"TYPE": {"voice"}})
+	}
+	for _, phone := range contact.Phones {
+		card.Add("TEL", phone.Number, map[string][]string{"TYPE": {vcardPhoneTypes[phone.Type]}})
+	}
+	for _, email := range contact.Emails {
+		card.Add("EMAIL", email.Address, vcardTypeParam(email.Type))
+	}
+	for _, address := range contact.Addresses {
+		// ADR is PO box;extended;street;locality;region;postal code;country.
+		adr := vcard.Structured("", "", address.Street, address.City, address.Region, address.PostalCode, address.Country)
+		adr.Params = vcardTypeParam(address.Type)
+		card.AddProperty("ADR", adr)
+	}
+	if contact.Company != "" {
+		card.AddProperty("ORG", vcard.Structured(contact.Company))
+	}
+	if contact.Title != "" {
+		card.Add("TITLE", contact.Title, nil)
+	}
+	if contact.Birthday != nil {
+		card.Add("BDAY", vcardDate(*contact.Birthday, version), nil)
+	}
+	if contact.Notes != "" {
+		card.Add("NOTE", contact.Notes, nil)
+	}
+
+	return card
+}
+
+// vcardPhoneTypes maps domain.PhoneTypes onto the TEL types of vCard.
+var vcardPhoneTypes = map[string]string{
+	"mobile": "cell",
+	"home":   "home",
+	"work":   "work",
+	"fax":    "fax",
+	"other":  "voice",
+}
+
+// vcardTypeParam returns the TYPE parameter of an email or address type.
+// "other" has no vCard counterpart and is written without one.
+func vcardTypeParam(t string) map[string][]string {
+	if t == "home" || t == "work" {
+		return map[string][]string{"TYPE": {t}}
+	}
+	return nil
+}
+
+// vcardDate formats a BDAY value: vCard 4.0 only allows the basic ISO 8601
+// format, while 3.0 readers expect the extended one.
+func vcardDate(date domain.Date, version string) string {
+	if version == "4.0" {
+		return strings.ReplaceAll(date.String(), "-", "")
+	}
+	return date.String()
+}
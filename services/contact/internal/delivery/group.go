@@ -7,6 +7,7 @@ import (
 
 	"advanced.microservices/pkg/helpers"
 	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/phone"
 	"advanced.microservices/pkg/validator"
 	"advanced.microservices/services/contact/internal/domain"
 	"advanced.microservices/services/contact/internal/repository"
@@ -15,23 +16,29 @@ import (
 
 type GroupHandler struct {
 	groupUseCase domain.GroupUseCase
+	phones       *phone.Parser
 	response     responseHandler
 }
 
-func NewGroupHandler(router *httprouter.Router, logger *jsonlog.Logger, groupUseCase domain.GroupUseCase) {
+func NewGroupHandler(router *httprouter.Router, logger *jsonlog.Logger, middleware *Middleware, groupUseCase domain.GroupUseCase, phones *phone.Parser) {
 	handler := &GroupHandler{
 		groupUseCase: groupUseCase,
+		phones:       phones,
 		response:     responseHandler{logger: logger},
 	}
-	router.HandlerFunc(http.MethodGet, "/group", handler.getById)
-	router.HandlerFunc(http.MethodPost, "/group", handler.create)
-	router.HandlerFunc(http.MethodPut, "/group", handler.update)
-	router.HandlerFunc(http.MethodGet, "/group/healthcheck", handler.healthcheck)
+	router.HandlerFunc(http.MethodGet, "/groups/:id", middleware.requirePermission(domain.PermissionContactsRead, handler.getById))
+	router.HandlerFunc(http.MethodPost, "/groups", middleware.requirePermission(domain.PermissionGroupsAdmin, handler.create))
+	router.HandlerFunc(http.MethodPut, "/groups/:id", middleware.requirePermission(domain.PermissionGroupsAdmin, handler.update))
+	router.HandlerFunc(http.MethodGet, "/groups-export", middleware.requirePermission(domain.PermissionContactsRead, handler.export))
+	router.HandlerFunc(http.MethodDelete, "/groups/:id", middleware.requirePermission(domain.PermissionGroupsAdmin, handler.delete))
+	router.HandlerFunc(http.MethodPut, "/groups/:id/parent", middleware.requirePermission(domain.PermissionGroupsAdmin, handler.move))
+	router.HandlerFunc(http.MethodGet, "/groups/:id/ancestors", middleware.requirePermission(domain.PermissionContactsRead, handler.ancestors))
+	router.HandlerFunc(http.MethodGet, "/groups/:id/descendants", middleware.requirePermission(domain.PermissionContactsRead, handler.descendants))
+	router.HandlerFunc(http.MethodGet, "/groups/:id/members", middleware.requirePermission(domain.PermissionContactsRead, handler.listMembers))
+	router.HandlerFunc(http.MethodPost, "/groups/:id/members", middleware.requirePermission(domain.PermissionGroupsAdmin, handler.addMember))
+	router.HandlerFunc(http.MethodDelete, "/groups/:id/members/:contact_id", middleware.requirePermission(domain.PermissionGroupsAdmin, handler.removeMember))
 }
 
-func (handler *GroupHandler) healthcheck(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, envelope{"status": "ok"}, nil)
-}
 func (handler *GroupHandler) getById(w http.ResponseWriter, r *http.Request) {
 	id, err := helpers.ReadIDParam(r)
 	if err != nil || id < 1 {
@@ -39,7 +46,7 @@ func (handler *GroupHandler) getById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, err := handler.groupUseCase.GetByID(id)
+	group, err := handler.groupUseCase.GetByID(r.Context(), id, contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrRecordNotFound):
@@ -59,6 +66,7 @@ func (handler *GroupHandler) getById(w http.ResponseWriter, r *http.Request) {
 func (handler *GroupHandler) create(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		GroupName string `json:"group_name"`
+		ParentID  *int64 `json:"parent_id"`
 	}
 
 	err := helpers.ReadJSON(w, r, &input)
@@ -69,24 +77,34 @@ func (handler *GroupHandler) create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	group := &domain.Group{
-		GroupName: input.GroupName,
+		OwnerID:   contextGetUser(r).ID,
+		ParentID:  input.ParentID,
+		GroupName: domain.NormalizeGroupName(input.GroupName),
 	}
 
 	v := validator.New()
 
 	if domain.ValidateGroup(v, group); !v.Valid() {
 		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
-	err = handler.groupUseCase.Create(group)
+	err = handler.groupUseCase.Create(r.Context(), group)
 
 	if err != nil {
-		handler.response.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, repository.ErrDuplicateGroupName):
+			handler.response.duplicateGroupNameResponse(w, r)
+		case errors.Is(err, repository.ErrInvalidParent):
+			handler.response.failedValidationResponse(w, r, map[string]string{"parent id": "must be an existing group"})
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/group/%d", group.ID))
+	headers.Set("Location", fmt.Sprintf("/groups/%d", group.ID))
 
 	err = writeJSON(w, http.StatusCreated, envelope{"group": group}, headers)
 	if err != nil {
@@ -101,7 +119,7 @@ func (handler *GroupHandler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, err := handler.groupUseCase.GetByID(id)
+	group, err := handler.groupUseCase.GetByID(r.Context(), id, contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrRecordNotFound):
@@ -124,7 +142,7 @@ func (handler *GroupHandler) update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if input.GroupName != nil {
-		group.GroupName = *input.GroupName
+		group.GroupName = domain.NormalizeGroupName(*input.GroupName)
 	}
 
 	v := validator.New()
@@ -134,15 +152,18 @@ func (handler *GroupHandler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = handler.groupUseCase.Update(group)
+	err = handler.groupUseCase.Update(r.Context(), group)
 
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrEditConflict):
 			handler.response.editConflictResponse(w, r)
+		case errors.Is(err, repository.ErrDuplicateGroupName):
+			handler.response.duplicateGroupNameResponse(w, r)
 		default:
 			handler.response.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 
 	err = writeJSON(w, http.StatusOK, envelope{"group": group}, nil)
@@ -152,3 +173,254 @@ func (handler *GroupHandler) update(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+func (handler *GroupHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	policy := domain.GroupDeletePolicy(helpers.ReadString(r.URL.Query(), "policy", string(domain.GroupDeleteRestrict)))
+
+	v := validator.New()
+
+	if domain.ValidateGroupDeletePolicy(v, policy); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.groupUseCase.Delete(r.Context(), id, contextGetUser(r).ID, policy)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		case errors.Is(err, repository.ErrGroupNotEmpty):
+			handler.response.groupNotEmptyResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "group successfully deleted"}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *GroupHandler) move(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	// A null or missing parent_id moves the group to the top level.
+	var input struct {
+		ParentID *int64 `json:"parent_id"`
+	}
+
+	err = helpers.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if domain.ValidateGroupParent(v, input.ParentID); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	group, err := handler.groupUseCase.Move(r.Context(), id, input.ParentID, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		case errors.Is(err, repository.ErrInvalidParent):
+			handler.response.failedValidationResponse(w, r, map[string]string{"parent id": "must be an existing group outside the moved subtree"})
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"group": group}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *GroupHandler) ancestors(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	groups, err := handler.groupUseCase.Ancestors(r.Context(), id, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"groups": groups}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *GroupHandler) descendants(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	groups, err := handler.groupUseCase.Descendants(r.Context(), id, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"groups": groups}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *GroupHandler) listMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	includeSubgroups := helpers.ReadBool(qs, "include_subgroups", false, v)
+	region := readRegion(qs, handler.phones, v)
+
+	if !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contacts, err := handler.groupUseCase.ListMembers(r.Context(), id, contextGetUser(r).ID, includeSubgroups)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	for _, contact := range contacts {
+		domain.FormatPhones(contact, handler.phones, region)
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"contacts": contacts}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *GroupHandler) addMember(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		ContactID int64 `json:"contact_id"`
+	}
+
+	err = helpers.ReadJSON(w, r, &input)
+
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	member := &domain.GroupMember{
+		GroupID:   id,
+		ContactID: input.ContactID,
+	}
+
+	v := validator.New()
+
+	if domain.ValidateGroupMember(v, member); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.groupUseCase.AddMember(r.Context(), member, contextGetUser(r).ID)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		case errors.Is(err, repository.ErrDuplicateMember):
+			handler.response.duplicateMemberResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/groups/%d/members", member.GroupID))
+
+	err = writeJSON(w, http.StatusCreated, envelope{"member": member}, headers)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *GroupHandler) removeMember(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	contactID, err := helpers.ReadNamedIDParam(r, "contact_id")
+	if err != nil || contactID < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	err = handler.groupUseCase.RemoveMember(r.Context(), id, contactID, contextGetUser(r).ID)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "member successfully removed"}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
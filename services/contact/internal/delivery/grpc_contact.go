@@ -0,0 +1,433 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/phone"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/repository"
+	pb "advanced.microservices/services/contact/protobuf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type ContactGRPCServer struct {
+	pb.UnimplementedContactServiceServer
+	contactUseCase domain.ContactUseCase
+	phones         *phone.Parser
+	logger         *jsonlog.Logger
+}
+
+func NewContactGRPCServer(server *grpc.Server, logger *jsonlog.Logger, contactUseCase domain.ContactUseCase, phones *phone.Parser) {
+	pb.RegisterContactServiceServer(server, &ContactGRPCServer{
+		contactUseCase: contactUseCase,
+		phones:         phones,
+		logger:         logger,
+	})
+}
+
+// CreateContact implements pb.ContactServiceServer
+func (server *ContactGRPCServer) CreateContact(ctx context.Context, req *pb.CreateContactRequest) (*pb.CreateContactResponse, error) {
+	user, err := requireAuthenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := server.region(req.GetRegion())
+	if err != nil {
+		return nil, err
+	}
+
+	birthday, err := fromProtoBirthday(req.GetBirthday())
+	if err != nil {
+		return nil, failedValidationStatus(map[string]string{"birthday": err.Error()})
+	}
+
+	contact := &domain.Contact{
+		OwnerID:      user.ID,
+		FullName:     req.GetFullName(),
+		Phone:        req.GetPhone(),
+		Phones:       fromProtoPhones(req.GetPhones()),
+		Emails:       fromProtoEmails(req.GetEmails()),
+		Addresses:    fromProtoAddresses(req.GetAddresses()),
+		Company:      req.GetCompany(),
+		Title:        req.GetTitle(),
+		Birthday:     birthday,
+		Notes:        req.GetNotes(),
+		CustomFields: req.GetCustomFields(),
+	}
+
+	domain.NormalizePhones(contact, server.phones)
+
+	v := validator.New()
+
+	if domain.ValidateContact(v, contact); !v.Valid() {
+		return nil, failedValidationStatus(v.Errors)
+	}
+
+	err = server.contactUseCase.Create(ctx, contact)
+	if err != nil {
+		return nil, server.errorStatus(ctx, "CreateContact", err)
+	}
+
+	domain.FormatPhones(contact, server.phones, region)
+	return &pb.CreateContactResponse{Contact: toProtoContact(contact)}, nil
+}
+
+// GetContact implements pb.ContactServiceServer
+func (server *ContactGRPCServer) GetContact(ctx context.Context, req *pb.GetContactRequest) (*pb.GetContactResponse, error) {
+	user, err := requireAuthenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := server.region(req.GetRegion())
+	if err != nil {
+		return nil, err
+	}
+
+	contact, err := server.contactUseCase.GetByID(ctx, req.GetId(), user.ID)
+	if err != nil {
+		return nil, server.errorStatus(ctx, "GetContact", err)
+	}
+
+	domain.FormatPhones(contact, server.phones, region)
+	return &pb.GetContactResponse{Contact: toProtoContact(contact)}, nil
+}
+
+// UpdateContact implements pb.ContactServiceServer
+func (server *ContactGRPCServer) UpdateContact(ctx context.Context, req *pb.UpdateContactRequest) (*pb.UpdateContactResponse, error) {
+	user, err := requireAuthenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := server.region(req.GetRegion())
+	if err != nil {
+		return nil, err
+	}
+
+	contact, err := server.contactUseCase.GetByID(ctx, req.GetId(), user.ID)
+	if err != nil {
+		return nil, server.errorStatus(ctx, "UpdateContact", err)
+	}
+
+	if req.FullName != nil {
+		contact.FullName = req.GetFullName()
+	}
+
+	if req.Phone != nil {
+		contact.Phone = req.GetPhone()
+	}
+
+	if req.Phones != nil {
+		contact.Phones = fromProtoPhones(req.Phones.GetItems())
+	}
+
+	if req.Emails != nil {
+		contact.Emails = fromProtoEmails(req.Emails.GetItems())
+	}
+
+	if req.Addresses != nil {
+		contact.Addresses = fromProtoAddresses(req.Addresses.GetItems())
+	}
+
+	if req.Company != nil {
+		contact.Company = req.GetCompany()
+	}
+
+	if req.Title != nil {
+		contact.Title = req.GetTitle()
+	}
+
+	if req.Birthday != nil {
+		contact.Birthday, err = fromProtoBirthday(req.GetBirthday())
+		if err != nil {
+			return nil, failedValidationStatus(map[string]string{"birthday": err.Error()})
+		}
+	}
+
+	if req.Notes != nil {
+		contact.Notes = req.GetNotes()
+	}
+
+	for _, key := range req.GetRemoveCustomFields() {
+		delete(contact.CustomFields, key)
+	}
+
+	for key, value := range req.GetCustomFields() {
+		if contact.CustomFields == nil {
+			contact.CustomFields = make(map[string]string)
+		}
+		contact.CustomFields[key] = value
+	}
+
+	domain.NormalizePhones(contact, server.phones)
+
+	v := validator.New()
+
+	if domain.ValidateContact(v, contact); !v.Valid() {
+		return nil, failedValidationStatus(v.Errors)
+	}
+
+	err = server.contactUseCase.Update(ctx, contact)
+	if err != nil {
+		return nil, server.errorStatus(ctx, "UpdateContact", err)
+	}
+
+	domain.FormatPhones(contact, server.phones, region)
+	return &pb.UpdateContactResponse{Contact: toProtoContact(contact)}, nil
+}
+
+// DeleteContact implements pb.ContactServiceServer
+func (server *ContactGRPCServer) DeleteContact(ctx context.Context, req *pb.DeleteContactRequest) (*pb.DeleteContactResponse, error) {
+	user, err := requireAuthenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.contactUseCase.Delete(ctx, req.GetId(), user.ID)
+	if err != nil {
+		return nil, server.errorStatus(ctx, "DeleteContact", err)
+	}
+
+	return &pb.DeleteContactResponse{}, nil
+}
+
+// ListContacts implements pb.ContactServiceServer
+func (server *ContactGRPCServer) ListContacts(ctx context.Context, req *pb.ListContactsRequest) (*pb.ListContactsResponse, error) {
+	user, err := requireAuthenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := server.region(req.GetRegion())
+	if err != nil {
+		return nil, err
+	}
+
+	filters := domain.Filters{
+		Page:         int(req.GetPage()),
+		PageSize:     int(req.GetPageSize()),
+		Sort:         req.GetSort(),
+		SortSafelist: domain.ContactSortSafelist,
+	}
+	if filters.Page == 0 {
+		filters.Page = 1
+	}
+	if filters.PageSize == 0 {
+		filters.PageSize = 20
+	}
+	if filters.Sort == "" {
+		filters.Sort = "id"
+	}
+
+	v := validator.New()
+
+	if domain.ValidateFilters(v, filters); !v.Valid() {
+		return nil, failedValidationStatus(v.Errors)
+	}
+
+	contacts, metadata, err := server.contactUseCase.List(ctx, user.ID, req.GetFullName(), req.GetPhone(), filters)
+	if err != nil {
+		return nil, server.errorStatus(ctx, "ListContacts", err)
+	}
+
+	res := &pb.ListContactsResponse{
+		Contacts: make([]*pb.Contact, 0, len(contacts)),
+		Metadata: &pb.Metadata{
+			CurrentPage:  int32(metadata.CurrentPage),
+			PageSize:     int32(metadata.PageSize),
+			FirstPage:    int32(metadata.FirstPage),
+			LastPage:     int32(metadata.LastPage),
+			TotalRecords: int32(metadata.TotalRecords),
+		},
+	}
+	for _, contact := range contacts {
+		domain.FormatPhones(contact, server.phones, region)
+		res.Contacts = append(res.Contacts, toProtoContact(contact))
+	}
+
+	return res, nil
+}
+
+// WatchContacts implements pb.ContactServiceServer. Only changes to the
+// caller's own contacts are streamed.
+func (server *ContactGRPCServer) WatchContacts(req *pb.WatchContactsRequest, stream pb.ContactService_WatchContactsServer) error {
+	user, err := requireAuthenticatedUser(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	region, err := server.region(req.GetRegion())
+	if err != nil {
+		return err
+	}
+
+	changes := server.contactUseCase.Watch(stream.Context())
+
+	for change := range changes {
+		if change.Contact.OwnerID != user.ID {
+			continue
+		}
+		domain.FormatPhones(&change.Contact, server.phones, region)
+		event := &pb.ContactEvent{
+			Type:    toProtoEventType(change.Type),
+			Contact: toProtoContact(&change.Contact),
+		}
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	return stream.Context().Err()
+}
+
+// region returns the region phone numbers are shown in, the default region
+// of the parser when the request leaves it empty.
+func (server *ContactGRPCServer) region(region string) (string, error) {
+	if region == "" {
+		return server.phones.DefaultRegion(), nil
+	}
+
+	region = strings.ToUpper(region)
+	if !server.phones.HasRegion(region) {
+		return "", failedValidationStatus(map[string]string{"region": "must be a supported region"})
+	}
+	return region, nil
+}
+
+func (server *ContactGRPCServer) errorStatus(ctx context.Context, method string, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "the requested resource could not be found")
+	case errors.Is(err, repository.ErrEditConflict):
+		return status.Error(codes.Aborted, "unable to update the record due to an edit conflict, please try again")
+	case errors.Is(err, domain.ErrCanceled):
+		return status.Error(codes.Canceled, "the request was canceled")
+	case errors.Is(err, domain.ErrTimeout):
+		return status.Error(codes.DeadlineExceeded, "the server took too long to process your request, please try again")
+	default:
+		server.logger.Ctx(ctx).PrintError(err, map[string]string{
+			"grpc_method": method,
+		})
+		return status.Error(codes.Internal, "the server encountered a problem and could not process your request")
+	}
+}
+
+func failedValidationStatus(errors map[string]string) error {
+	keys := make([]string, 0, len(errors))
+	for key := range errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, 0, len(keys))
+	for _, key := range keys {
+		messages = append(messages, key+": "+errors[key])
+	}
+
+	return status.Error(codes.InvalidArgument, strings.Join(messages, "; "))
+}
+
+func toProtoContact(contact *domain.Contact) *pb.Contact {
+	res := &pb.Contact{
+		Id:           contact.ID,
+		FullName:     contact.FullName,
+		Phone:        contact.Phone,
+		PhoneE164:    contact.PhoneE164,
+		Version:      contact.Version,
+		Company:      contact.Company,
+		Title:        contact.Title,
+		Notes:        contact.Notes,
+		CustomFields: contact.CustomFields,
+	}
+	if !contact.CreatedAt.IsZero() {
+		res.CreatedAt = timestamppb.New(contact.CreatedAt)
+	}
+	if contact.Birthday != nil {
+		res.Birthday = contact.Birthday.String()
+	}
+	for _, phone := range contact.Phones {
+		res.Phones = append(res.Phones, &pb.PhoneNumber{Type: phone.Type, Number: phone.Number, E164: phone.E164})
+	}
+	for _, email := range contact.Emails {
+		res.Emails = append(res.Emails, &pb.Email{Type: email.Type, Address: email.Address})
+	}
+	for _, address := range contact.Addresses {
+		res.Addresses = append(res.Addresses, &pb.Address{
+			Type:       address.Type,
+			Street:     address.Street,
+			City:       address.City,
+			Region:     address.Region,
+			PostalCode: address.PostalCode,
+			Country:    address.Country,
+		})
+	}
+	return res
+}
+
+func fromProtoPhones(phones []*pb.PhoneNumber) []domain.PhoneNumber {
+	var res []domain.PhoneNumber
+	for _, phone := range phones {
+		res = append(res, domain.PhoneNumber{Type: phone.GetType(), Number: phone.GetNumber()})
+	}
+	return res
+}
+
+func fromProtoEmails(emails []*pb.Email) []domain.Email {
+	var res []domain.Email
+	for _, email := range emails {
+		res = append(res, domain.Email{Type: email.GetType(), Address: email.GetAddress()})
+	}
+	return res
+}
+
+func fromProtoAddresses(addresses []*pb.Address) []domain.Address {
+	var res []domain.Address
+	for _, address := range addresses {
+		res = append(res, domain.Address{
+			Type:       address.GetType(),
+			Street:     address.GetStreet(),
+			City:       address.GetCity(),
+			Region:     address.GetRegion(),
+			PostalCode: address.GetPostalCode(),
+			Country:    address.GetCountry(),
+		})
+	}
+	return res
+}
+
+// fromProtoBirthday parses a "YYYY-MM-DD" birthday. The empty string stands
+// for no birthday, as proto3 strings cannot be null.
+func fromProtoBirthday(s string) (*domain.Date, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	birthday, err := domain.ParseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &birthday, nil
+}
+
+func toProtoEventType(changeType domain.ChangeType) pb.ContactEvent_Type {
+	switch changeType {
+	case domain.ChangeCreated:
+		return pb.ContactEvent_TYPE_CREATED
+	case domain.ChangeUpdated:
+		return pb.ContactEvent_TYPE_UPDATED
+	case domain.ChangeDeleted:
+		return pb.ContactEvent_TYPE_DELETED
+	default:
+		return pb.ContactEvent_TYPE_UNSPECIFIED
+	}
+}
@@ -0,0 +1,76 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"advanced.microservices/pkg/jsonlog"
+	pb "advanced.microservices/services/contact/protobuf"
+	"google.golang.org/grpc"
+)
+
+type GreetServer struct {
+	pb.UnimplementedGreetServiceServer
+	logger *jsonlog.Logger
+}
+
+func NewGreetServer(server *grpc.Server, logger *jsonlog.Logger) {
+	pb.RegisterGreetServiceServer(server, &GreetServer{logger: logger})
+}
+
+func greeting(g *pb.Greeting) string {
+	return strings.TrimSpace(fmt.Sprintf("Hello %s %s", g.GetFirstName(), g.GetLastName()))
+}
+
+// Greet implements pb.GreetServiceServer
+func (server *GreetServer) Greet(ctx context.Context, req *pb.GreetRequest) (*pb.GreetResponse, error) {
+	return &pb.GreetResponse{Result: greeting(req.GetGreeting())}, nil
+}
+
+// GreetManyTimes implements pb.GreetServiceServer
+func (server *GreetServer) GreetManyTimes(req *pb.GreetManyTimesRequest, stream pb.GreetService_GreetManyTimesServer) error {
+	for i := 1; i <= 10; i++ {
+		err := stream.Send(&pb.GreetManyTimesResponse{
+			Result: fmt.Sprintf("%s, number %d", greeting(req.GetGreeting()), i),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LongGreet implements pb.GreetServiceServer
+func (server *GreetServer) LongGreet(stream pb.GreetService_LongGreetServer) error {
+	var greetings []string
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&pb.LongGreetResponse{Result: strings.Join(greetings, "! ")})
+		}
+		if err != nil {
+			return err
+		}
+		greetings = append(greetings, greeting(req.GetGreeting()))
+	}
+}
+
+// GreetEveryone implements pb.GreetServiceServer
+func (server *GreetServer) GreetEveryone(stream pb.GreetService_GreetEveryoneServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = stream.Send(&pb.GreetEveryoneResponse{Result: greeting(req.GetGreeting()) + "!"})
+		if err != nil {
+			return err
+		}
+	}
+}
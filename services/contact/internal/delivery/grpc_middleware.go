@@ -0,0 +1,202 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/repository"
+	pb "advanced.microservices/services/contact/protobuf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcPermissions is the gRPC counterpart of the requirePermission wrappers:
+// it maps the full method name of each protected RPC to the permission code
+// the caller must hold.
+var grpcPermissions = map[string]string{
+	pb.ContactService_CreateContact_FullMethodName: domain.PermissionContactsWrite,
+	pb.ContactService_GetContact_FullMethodName:    domain.PermissionContactsRead,
+	pb.ContactService_UpdateContact_FullMethodName: domain.PermissionContactsWrite,
+	pb.ContactService_DeleteContact_FullMethodName: domain.PermissionContactsWrite,
+	pb.ContactService_ListContacts_FullMethodName:  domain.PermissionContactsRead,
+	pb.ContactService_WatchContacts_FullMethodName: domain.PermissionContactsRead,
+}
+
+// UnaryRequestID is the gRPC counterpart of RequestID: it reads the
+// x-request-id and traceparent metadata, generating a request ID when missing,
+// and adds them with the method to the log fields of the call context.
+func (middleware *Middleware) UnaryRequestID(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return handler(grpcLogContext(ctx, md, info.FullMethod), req)
+}
+
+// StreamRequestID is the streaming counterpart of UnaryRequestID.
+func (middleware *Middleware) StreamRequestID(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	ctx := grpcLogContext(stream.Context(), md, info.FullMethod)
+	return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+}
+
+// UnaryRecoverPanic is the gRPC counterpart of RecoverPanic: a panicking
+// handler is logged and answered with an Internal status instead of
+// crashing the server.
+func (middleware *Middleware) UnaryRecoverPanic(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = middleware.panicStatus(ctx, info.FullMethod, recovered)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// StreamRecoverPanic is the streaming counterpart of UnaryRecoverPanic.
+func (middleware *Middleware) StreamRecoverPanic(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = middleware.panicStatus(stream.Context(), info.FullMethod, recovered)
+		}
+	}()
+
+	return handler(srv, stream)
+}
+
+func (middleware *Middleware) panicStatus(ctx context.Context, method string, recovered any) error {
+	middleware.response.logger.Ctx(ctx).PrintError(fmt.Errorf("%v", recovered), map[string]string{
+		"grpc_method": method,
+	})
+	return status.Error(codes.Internal, "the server encountered a problem and could not process your request")
+}
+
+// UnaryAuthenticate is the gRPC counterpart of Authenticate: the bearer token
+// is read from the "authorization" metadata key. Methods listed in
+// grpcPermissions also require the matching permission.
+func (middleware *Middleware) UnaryAuthenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := middleware.authenticateContext(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamAuthenticate is the streaming counterpart of UnaryAuthenticate.
+func (middleware *Middleware) StreamAuthenticate(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := middleware.authenticateContext(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+}
+
+func (middleware *Middleware) authenticateContext(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+
+	if len(values) == 0 {
+		if _, ok := grpcPermissions[method]; ok {
+			return nil, status.Error(codes.Unauthenticated, "you must be authenticated to access this resource")
+		}
+		return context.WithValue(ctx, userContextKey, domain.AnonymousUser), nil
+	}
+
+	invalid := status.Error(codes.Unauthenticated, "invalid or missing authentication token")
+	internalStatus := status.Error(codes.Internal, "the server encountered a problem and could not process your request")
+
+	headerParts := strings.Split(values[0], " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return nil, invalid
+	}
+
+	token := headerParts[1]
+
+	v := validator.New()
+
+	if domain.ValidateTokenPlaintext(v, token); !v.Valid() {
+		return nil, invalid
+	}
+
+	user, err := middleware.userUseCase.GetForToken(ctx, domain.ScopeAuthentication, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			return nil, invalid
+		default:
+			middleware.response.logger.Ctx(ctx).PrintError(err, map[string]string{
+				"grpc_method": method,
+			})
+			return nil, internalStatus
+		}
+	}
+
+	ctx = context.WithValue(ctx, userContextKey, user)
+	ctx = domain.NewActorContext(ctx, user.ID)
+	jsonlog.AddFields(ctx, jsonlog.Int64(jsonlog.KeyUserID, user.ID))
+
+	code, ok := grpcPermissions[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	permissions, err := middleware.userUseCase.GetPermissions(ctx, user.ID)
+	if err != nil {
+		middleware.response.logger.Ctx(ctx).PrintError(err, map[string]string{
+			"grpc_method": method,
+		})
+		return nil, internalStatus
+	}
+
+	if !permissions.Include(code) {
+		return nil, status.Error(codes.PermissionDenied, "your user account doesn't have the necessary permissions to access this resource")
+	}
+
+	return ctx, nil
+}
+
+// grpcLogContext returns a copy of ctx carrying the log fields of a call.
+func grpcLogContext(ctx context.Context, md metadata.MD, method string) context.Context {
+	requestID := ""
+	if values := md.Get("x-request-id"); len(values) > 0 && validRequestID(values[0]) {
+		requestID = values[0]
+	} else if generated, err := generateRequestID(); err == nil {
+		requestID = generated
+	}
+
+	fields := []jsonlog.Field{jsonlog.String(jsonlog.KeyRoute, method)}
+	if requestID != "" {
+		fields = append(fields, jsonlog.String(jsonlog.KeyRequestID, requestID))
+		grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+	}
+	if values := md.Get("traceparent"); len(values) > 0 {
+		if traceID, ok := parseTraceParent(values[0]); ok {
+			fields = append(fields, jsonlog.String(jsonlog.KeyTraceID, traceID))
+		}
+	}
+
+	return jsonlog.NewContext(ctx, fields...)
+}
+
+// requireAuthenticatedUser returns the user stored by the interceptors, or an
+// Unauthenticated status for anonymous callers.
+func requireAuthenticatedUser(ctx context.Context) (*domain.User, error) {
+	user, ok := userFromContext(ctx)
+	if !ok || user.IsAnonymous() {
+		return nil, status.Error(codes.Unauthenticated, "you must be authenticated to access this resource")
+	}
+	return user, nil
+}
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *contextServerStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,65 @@
+package delivery
+
+import (
+	"net/http"
+
+	"advanced.microservices/pkg/health"
+	"advanced.microservices/pkg/jsonlog"
+	"github.com/julienschmidt/httprouter"
+)
+
+type HealthHandler struct {
+	health   *health.Health
+	version  string
+	env      string
+	response responseHandler
+}
+
+func NewHealthHandler(router *httprouter.Router, logger *jsonlog.Logger, h *health.Health, version string, env string) {
+	handler := &HealthHandler{
+		health:   h,
+		version:  version,
+		env:      env,
+		response: responseHandler{logger: logger},
+	}
+	router.HandlerFunc(http.MethodGet, "/livez", handler.livez)
+	router.HandlerFunc(http.MethodGet, "/readyz", handler.readyz)
+}
+
+func (handler *HealthHandler) livez(w http.ResponseWriter, r *http.Request) {
+	env := envelope{
+		"status": health.StatusPass,
+		"system_info": map[string]string{
+			"environment": handler.env,
+			"version":     handler.version,
+		},
+	}
+
+	err := writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *HealthHandler) readyz(w http.ResponseWriter, r *http.Request) {
+	report := handler.health.Ready(r.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusPass {
+		status = http.StatusServiceUnavailable
+	}
+
+	env := envelope{
+		"status": report.Status,
+		"checks": report.Checks,
+		"system_info": map[string]string{
+			"environment": handler.env,
+			"version":     handler.version,
+		},
+	}
+
+	err := writeJSON(w, status, env, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
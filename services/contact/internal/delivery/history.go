@@ -0,0 +1,119 @@
+package delivery
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"advanced.microservices/pkg/helpers"
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/repository"
+	"github.com/julienschmidt/httprouter"
+)
+
+type HistoryHandler struct {
+	historyUseCase domain.HistoryUseCase
+	response       responseHandler
+}
+
+func NewHistoryHandler(router *httprouter.Router, logger *jsonlog.Logger, middleware *Middleware, historyUseCase domain.HistoryUseCase) {
+	handler := &HistoryHandler{
+		historyUseCase: historyUseCase,
+		response:       responseHandler{logger: logger},
+	}
+	router.HandlerFunc(http.MethodGet, "/contacts/:id/history", middleware.requirePermission(domain.PermissionContactsRead, handler.list(domain.EntityContact)))
+	router.HandlerFunc(http.MethodGet, "/contacts/:id/history/diff", middleware.requirePermission(domain.PermissionContactsRead, handler.diff(domain.EntityContact)))
+	router.HandlerFunc(http.MethodGet, "/groups/:id/history", middleware.requirePermission(domain.PermissionContactsRead, handler.list(domain.EntityGroup)))
+	router.HandlerFunc(http.MethodGet, "/groups/:id/history/diff", middleware.requirePermission(domain.PermissionContactsRead, handler.diff(domain.EntityGroup)))
+}
+
+// list serves the history of a record of entityType, oldest version first
+// unless sorted by -version. The history of a deleted record stays readable.
+func (handler *HistoryHandler) list(entityType domain.EntityType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := helpers.ReadIDParam(r)
+		if err != nil || id < 1 {
+			handler.response.notFoundResponse(w, r)
+			return
+		}
+
+		var input struct {
+			domain.Filters
+		}
+
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		input.Filters.Page = helpers.ReadInt(qs, "page", 1, v)
+		input.Filters.PageSize = helpers.ReadInt(qs, "page_size", 20, v)
+		input.Filters.Sort = helpers.ReadString(qs, "sort", "version")
+		input.Filters.SortSafelist = domain.HistorySortSafelist
+
+		if domain.ValidateFilters(v, input.Filters); !v.Valid() {
+			handler.response.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		entries, metadata, err := handler.historyUseCase.List(r.Context(), entityType, id, contextGetUser(r).ID, input.Filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrRecordNotFound):
+				handler.response.notFoundResponse(w, r)
+			default:
+				handler.response.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"history": entries, "metadata": metadata}, nil)
+		if err != nil {
+			handler.response.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// diff serves the fields of a record of entityType that differ between the
+// versions given by the from and to query parameters.
+func (handler *HistoryHandler) diff(entityType domain.EntityType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := helpers.ReadIDParam(r)
+		if err != nil || id < 1 {
+			handler.response.notFoundResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		qs := r.URL.Query()
+
+		from := helpers.ReadInt(qs, "from", 0, v)
+		to := helpers.ReadInt(qs, "to", 0, v)
+
+		v.Check(from > 0 && from <= math.MaxInt32, "from", "must be a positive integer")
+		v.Check(to > 0 && to <= math.MaxInt32, "to", "must be a positive integer")
+
+		if !v.Valid() {
+			handler.response.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		changes, err := handler.historyUseCase.Diff(r.Context(), entityType, id, contextGetUser(r).ID, int32(from), int32(to))
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrRecordNotFound):
+				handler.response.notFoundResponse(w, r)
+			default:
+				handler.response.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"from": from, "to": to, "changes": changes}, nil)
+		if err != nil {
+			handler.response.serverErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+package delivery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"advanced.microservices/pkg/helpers"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/pkg/vcard"
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+const (
+	maxImportBytes = 10 << 20
+	maxImportRows  = 10_000
+)
+
+var errUnknownImportFormat = errors.New("unable to detect the import format, use ?format=csv or ?format=vcard")
+
+type importRow struct {
+	Row     int               `json:"row"`
+	Status  string            `json:"status"`
+	Contact *domain.Contact   `json:"contact,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+type importReport struct {
+	Accepted int          `json:"accepted"`
+	Rejected int          `json:"rejected"`
+	Rows     []*importRow `json:"rows"`
+}
+
+func (handler *ContactHandler) importContacts(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	v := validator.New()
+
+	region := readRegion(r.URL.Query(), handler.phones, v)
+
+	if !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	source, format, err := importSource(r)
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+	defer source.Close()
+
+	var rows []*importRow
+
+	switch format {
+	case "csv":
+		rows, err = readCSVContacts(source)
+	case "vcard":
+		rows, err = readVCardContacts(source)
+	}
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			err = fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	report := importReport{Rows: rows}
+	var accepted []*domain.Contact
+
+	ownerID := contextGetUser(r).ID
+
+	for _, row := range rows {
+		if row.Errors == nil {
+			domain.NormalizePhones(row.Contact, handler.phones)
+
+			v := validator.New()
+			if domain.ValidateContact(v, row.Contact); !v.Valid() {
+				row.Errors = v.Errors
+			}
+		}
+
+		if row.Errors != nil {
+			row.Status = "rejected"
+			report.Rejected++
+			continue
+		}
+
+		row.Status = "accepted"
+		row.Contact.OwnerID = ownerID
+		report.Accepted++
+		accepted = append(accepted, row.Contact)
+	}
+
+	if len(accepted) > 0 {
+		err = handler.contactUseCase.CreateMany(r.Context(), accepted)
+		if err != nil {
+			handler.response.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	for _, contact := range accepted {
+		domain.FormatPhones(contact, handler.phones, region)
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"import": report}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+// importSource returns the uploaded document, either the "file" part of a
+// multipart form or the raw request body, together with its format. The
+// format is taken from the ?format= parameter, the file extension or the
+// content type, in that order.
+func importSource(r *http.Request) (io.ReadCloser, string, error) {
+	format := strings.ToLower(helpers.ReadString(r.URL.Query(), "format", ""))
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	source := r.Body
+	filename := ""
+
+	if contentType == "multipart/form-data" {
+		err := r.ParseMultipartForm(maxImportBytes)
+		if err != nil {
+			return nil, "", err
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("multipart body must contain a \"file\" part")
+		}
+		source = file
+		filename = header.Filename
+		contentType, _, _ = mime.ParseMediaType(header.Header.Get("Content-Type"))
+	}
+
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".csv":
+			format = "csv"
+		case ".vcf", ".vcard":
+			format = "vcard"
+		}
+	}
+
+	if format == "" {
+		switch contentType {
+		case "text/csv", "application/csv":
+			format = "csv"
+		case "text/vcard", "text/x-vcard", "text/directory":
+			format = "vcard"
+		}
+	}
+
+	if format != "csv" && format != "vcard" {
+		source.Close()
+		return nil, "", errUnknownImportFormat
+	}
+
+	return source, format, nil
+}
+
+// readCSVContacts reads a CSV document whose header row names the columns.
+// Column names are matched case-insensitively and a few common aliases are
+// understood. The phones, emails, addresses and custom_fields columns hold
+// JSON, as written by the CSV export; a plain email column is read as a
+// single address.
+func readCSVContacts(source io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(source)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("csv document must not be empty")
+		}
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+		switch name {
+		case "full_name", "fullname", "name":
+			columns["full_name"] = i
+		case "phone", "phone_number", "tel", "telephone":
+			columns["phone"] = i
+		case "email", "e-mail", "email_address":
+			columns["email"] = i
+		case "company", "organization", "org":
+			columns["company"] = i
+		case "title", "job_title":
+			columns["title"] = i
+		case "birthday", "bday", "birth_date":
+			columns["birthday"] = i
+		case "notes", "note":
+			columns["notes"] = i
+		case "phones", "emails", "addresses", "custom_fields":
+			columns[name] = i
+		}
+	}
+
+	if _, ok := columns["full_name"]; !ok {
+		return nil, errors.New("csv header must contain a full_name column")
+	}
+	if _, ok := columns["phone"]; !ok {
+		return nil, errors.New("csv header must contain a phone column")
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []*importRow
+
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if n > maxImportRows {
+			return nil, fmt.Errorf("import must not contain more than %d rows", maxImportRows)
+		}
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			rows = append(rows, &importRow{Row: n, Errors: map[string]string{"row": parseError.Err.Error()}})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := &importRow{
+			Row: n,
+			Contact: &domain.Contact{
+				FullName: field(record, "full_name"),
+				Phone:    field(record, "phone"),
+				Company:  field(record, "company"),
+				Title:    field(record, "title"),
+				Notes:    field(record, "notes"),
+			},
+		}
+		readCSVDetails(row, func(column string) string { return field(record, column) })
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// readCSVDetails fills in the columns of row that need parsing, recording a
+// row error for each one that cannot be read.
+func readCSVDetails(row *importRow, field func(column string) string) {
+	contact := row.Contact
+	fail := func(column string, message string) {
+		if row.Errors == nil {
+			row.Errors = make(map[string]string)
+		}
+		row.Errors[column] = message
+	}
+
+	if value := field("birthday"); value != "" {
+		birthday, err := domain.ParseDate(value)
+		if err != nil {
+			fail("birthday", err.Error())
+		} else {
+			contact.Birthday = &birthday
+		}
+	}
+
+	if value := field("phones"); value != "" && json.Unmarshal([]byte(value), &contact.Phones) != nil {
+		fail("phones", `must be a JSON array of {"type", "number"} objects`)
+	}
+	if value := field("emails"); value != "" && json.Unmarshal([]byte(value), &contact.Emails) != nil {
+		fail("emails", `must be a JSON array of {"type", "address"} objects`)
+	}
+	if value := field("addresses"); value != "" && json.Unmarshal([]byte(value), &contact.Addresses) != nil {
+		fail("addresses", "must be a JSON array of address objects")
+	}
+	if value := field("custom_fields"); value != "" && json.Unmarshal([]byte(value), &contact.CustomFields) != nil {
+		fail("custom_fields", "must be a JSON object of string values")
+	}
+
+	if value := field("email"); value != "" {
+		contact.Emails = append(contact.Emails, domain.Email{Type: "other", Address: value})
+	}
+}
+
+// readVCardContacts reads vCard 3.0 and 4.0 documents containing any number
+// of cards.
+func readVCardContacts(source io.Reader) ([]*importRow, error) {
+	decoder := vcard.NewDecoder(source)
+
+	var rows []*importRow
+
+	for n := 1; ; n++ {
+		card, err := decoder.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n > maxImportRows {
+			return nil, fmt.Errorf("import must not contain more than %d rows", maxImportRows)
+		}
+
+		contact, rowErrors := contactFromVCard(card)
+		rows = append(rows, &importRow{
+			Row:     n,
+			Contact: contact,
+			Errors:  rowErrors,
+		})
+	}
+
+	return rows, nil
+}
+
+// contactFromVCard maps the properties of card onto a contact. The preferred
+// TEL, or else the first one, becomes the primary phone and the others are
+// added to Phones. A BDAY that is not a full date is reported in the returned
+// errors.
+func contactFromVCard(card vcard.Card) (*domain.Contact, map[string]string) {
+	contact := &domain.Contact{
+		FullName: strings.TrimSpace(card.Value("FN")),
+		Title:    strings.TrimSpace(card.Value("TITLE")),
+		Notes:    strings.TrimSpace(card.Value("NOTE")),
+	}
+
+	if contact.FullName == "" {
+		if n, ok := card.Get("N"); ok {
+			// N is family;given;additional;prefix;suffix.
+			components := n.Components()
+			var parts []string
+			for i := 0; i < len(components) && i < 3; i++ {
+				if part := strings.TrimSpace(components[i]); part != "" {
+					parts = append(parts, part)
+				}
+			}
+			contact.FullName = strings.Join(parts, " ")
+		}
+	}
+
+	phones := card.All("TEL")
+	primary := -1
+	for i, phone := range phones {
+		if isPreferred(phone) {
+			primary = i
+			break
+		}
+	}
+	if primary < 0 && len(phones) > 0 {
+		primary = 0
+	}
+	for i, phone := range phones {
+		if i == primary {
+			contact.Phone = telValue(phone)
+			continue
+		}
+		contact.Phones = append(contact.Phones, domain.PhoneNumber{Type: phoneType(phone), Number: telValue(phone)})
+	}
+
+	for _, email := range card.All("EMAIL") {
+		contact.Emails = append(contact.Emails, domain.Email{Type: entryType(email), Address: strings.TrimSpace(email.Value)})
+	}
+
+	for _, adr := range card.All("ADR") {
+		// ADR is PO box;extended;street;locality;region;postal code;country.
+		components := make([]string, 7)
+		copy(components, adr.Components())
+		street := strings.TrimSpace(components[2])
+		if extended := strings.TrimSpace(components[1]); extended != "" {
+			street = strings.TrimSpace(street + " " + extended)
+		}
+		contact.Addresses = append(contact.Addresses, domain.Address{
+			Type:       entryType(adr),
+			Street:     street,
+			City:       strings.TrimSpace(components[3]),
+			Region:     strings.TrimSpace(components[4]),
+			PostalCode: strings.TrimSpace(components[5]),
+			Country:    strings.TrimSpace(components[6]),
+		})
+	}
+
+	if org, ok := card.Get("ORG"); ok {
+		// ORG is organization;unit;...; only the organization is kept.
+		contact.Company = strings.TrimSpace(org.Components()[0])
+	}
+
+	var rowErrors map[string]string
+	if bday := strings.TrimSpace(card.Value("BDAY")); bday != "" {
+		birthday, err := parseVCardDate(bday)
+		if err != nil {
+			rowErrors = map[string]string{"birthday": err.Error()}
+		} else {
+			contact.Birthday = &birthday
+		}
+	}
+
+	return contact, rowErrors
+}
+
+// parseVCardDate parses a BDAY in the extended (vCard 3.0) or basic (vCard
+// 4.0) ISO 8601 date format. Dates without a year, such as --0415, cannot be
+// stored and are rejected.
+func parseVCardDate(value string) (domain.Date, error) {
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return domain.DateOf(t), nil
+		}
+	}
+	return domain.Date{}, fmt.Errorf("invalid date %q, expected a full date such as 1990-04-15 or 19900415", value)
+}
+
+// phoneType maps the TYPE parameters of a TEL onto domain.PhoneTypes.
+func phoneType(property vcard.Property) string {
+	for _, t := range property.Params["TYPE"] {
+		switch strings.ToLower(t) {
+		case "cell", "mobile", "iphone":
+			return "mobile"
+		case "home", "work", "fax":
+			return strings.ToLower(t)
+		}
+	}
+	return "other"
+}
+
+// entryType maps the TYPE parameters of an EMAIL or ADR onto
+// domain.EmailTypes and domain.AddressTypes.
+func entryType(property vcard.Property) string {
+	for _, t := range property.Params["TYPE"] {
+		switch strings.ToLower(t) {
+		case "home", "work":
+			return strings.ToLower(t)
+		}
+	}
+	return "other"
+}
+
+func isPreferred(property vcard.Property) bool {
+	if len(property.Params["PREF"]) > 0 {
+		return true
+	}
+	for _, t := range property.Params["TYPE"] {
+		if strings.EqualFold(t, "pref") {
+			return true
+		}
+	}
+	return false
+}
+
+// telValue strips the "tel:" URI scheme used by vCard 4.0.
+func telValue(property vcard.Property) string {
+	value := strings.TrimSpace(property.Value)
+	if len(value) >= 4 && strings.EqualFold(value[:4], "tel:") {
+		value = value[4:]
+	}
+	return value
+}
@@ -0,0 +1,79 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/metrics"
+	"github.com/julienschmidt/httprouter"
+)
+
+// unmatchedRoute labels requests that no route handles, so that scans of
+// random paths cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+// Metrics records request counts, latencies, status codes and in-flight
+// requests for the HTTP API.
+type Metrics struct {
+	router    *httprouter.Router
+	requests  *metrics.CounterVec
+	duration  *metrics.HistogramVec
+	responses *metrics.CounterVec
+	inFlight  *metrics.Gauge
+}
+
+func NewMetrics(registry *metrics.Registry, router *httprouter.Router) *Metrics {
+	return &Metrics{
+		router:    router,
+		requests:  metrics.NewCounterVec(registry, "http_requests_total", "Total number of HTTP requests by route.", "method", "route"),
+		duration:  metrics.NewHistogramVec(registry, "http_request_duration_seconds", "HTTP request latency by route.", metrics.DefBuckets, "method", "route"),
+		responses: metrics.NewCounterVec(registry, "http_responses_total", "Total number of HTTP responses by status code.", "code"),
+		inFlight:  metrics.NewGauge(registry, "http_requests_in_flight", "Number of HTTP requests currently being served."),
+	}
+}
+
+// Instrument also adds the matched route to the log fields of the request.
+func (m *Metrics) Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := m.route(r)
+		jsonlog.AddFields(r.Context(), jsonlog.String(jsonlog.KeyRoute, route))
+
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		m.requests.Inc(r.Method, route)
+		m.duration.Observe(time.Since(start).Seconds(), r.Method, route)
+		m.responses.Inc(strconv.Itoa(recorder.status))
+	})
+}
+
+// route rebuilds the pattern the request matches, e.g. /contacts/:id/groups,
+// from the parameters httprouter extracts. httprouter parameters always span
+// whole path segments.
+func (m *Metrics) route(r *http.Request) string {
+	handle, params, _ := m.router.Lookup(r.Method, r.URL.Path)
+	if handle == nil {
+		return unmatchedRoute
+	}
+	if len(params) == 0 {
+		return r.URL.Path
+	}
+
+	segments := strings.Split(r.URL.Path, "/")
+	next := 0
+	for i := range segments {
+		if next < len(params) && segments[i] == params[next].Value {
+			segments[i] = ":" + params[next].Key
+			next++
+		}
+	}
+	return strings.Join(segments, "/")
+}
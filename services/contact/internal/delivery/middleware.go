@@ -0,0 +1,108 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/repository"
+)
+
+type Middleware struct {
+	userUseCase domain.UserUseCase
+	response    responseHandler
+}
+
+func NewMiddleware(logger *jsonlog.Logger, userUseCase domain.UserUseCase) *Middleware {
+	return &Middleware{
+		userUseCase: userUseCase,
+		response:    responseHandler{logger: logger},
+	}
+}
+
+// Authenticate resolves the bearer token of the Authorization header into a
+// user and stores it in the request context. Requests without the header
+// carry domain.AnonymousUser.
+func (middleware *Middleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+
+		if authorizationHeader == "" {
+			r = contextSetUser(r, domain.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			middleware.response.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		token := headerParts[1]
+
+		v := validator.New()
+
+		if domain.ValidateTokenPlaintext(v, token); !v.Valid() {
+			middleware.response.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := middleware.userUseCase.GetForToken(r.Context(), domain.ScopeAuthentication, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrRecordNotFound):
+				middleware.response.invalidAuthenticationTokenResponse(w, r)
+			default:
+				middleware.response.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		r = contextSetUser(r, user)
+		jsonlog.AddFields(r.Context(), jsonlog.Int64(jsonlog.KeyUserID, user.ID))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (handler *responseHandler) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := contextGetUser(r)
+
+		if user.IsAnonymous() {
+			handler.authenticationRequiredResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requirePermission only lets authenticated users holding the given
+// permission code through.
+func (middleware *Middleware) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := contextGetUser(r)
+
+		permissions, err := middleware.userUseCase.GetPermissions(r.Context(), user.ID)
+		if err != nil {
+			middleware.response.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !permissions.Include(code) {
+			middleware.response.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return middleware.response.requireAuthenticatedUser(fn)
+}
@@ -0,0 +1,325 @@
+package delivery
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"advanced.microservices/pkg/jsonlog"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// limiterEvictInterval is how often the client table is swept.
+	limiterEvictInterval = time.Minute
+	// limiterClientTTL is how long a client may stay idle before its bucket
+	// is dropped. A dropped client simply starts again with a full bucket.
+	limiterClientTTL = 3 * time.Minute
+)
+
+// LimiterConfig configures RateLimiter. RPS and Burst size the bucket of each
+// client; GlobalRPS and GlobalBurst size the bucket shared by all of them, a
+// GlobalRPS of 0 disabling it.
+type LimiterConfig struct {
+	Enabled     bool
+	RPS         float64
+	Burst       int
+	GlobalRPS   float64
+	GlobalBurst int
+}
+
+type limiterClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter throttles requests with token buckets. Requests are keyed by
+// the client IP until Authenticate has resolved their user, and by the user
+// from then on, so that users behind a shared address do not starve each
+// other while forged tokens cannot dodge the limit.
+type RateLimiter struct {
+	config   LimiterConfig
+	global   *rate.Limiter
+	mu       sync.Mutex
+	clients  map[string]*limiterClient
+	response responseHandler
+}
+
+func NewRateLimiter(logger *jsonlog.Logger, config LimiterConfig) *RateLimiter {
+	limiter := &RateLimiter{
+		config:   config,
+		clients:  make(map[string]*limiterClient),
+		response: responseHandler{logger: logger},
+	}
+	if config.GlobalRPS > 0 {
+		limiter.global = rate.NewLimiter(rate.Limit(config.GlobalRPS), config.GlobalBurst)
+	}
+	return limiter
+}
+
+// RateLimit rejects requests over the limit of their IP with 429 Too Many
+// Requests and must run before Authenticate. Anonymous requests take a token
+// from the bucket of their IP. Requests carrying credentials are only turned
+// away while that bucket is empty, and take a token from it when Authenticate
+// rejects their credentials; the others are left to RateLimitUser. Responses
+// to anonymous requests carry the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers of the IP bucket.
+func (limiter *RateLimiter) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !limiter.config.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			limiter.response.serverErrorResponse(w, r, err)
+			return
+		}
+
+		bucket := limiter.client("ip:" + ip)
+
+		anonymous := r.Header.Get("Authorization") == ""
+		if anonymous {
+			allowed := bucket.Allow()
+			setRateLimitHeaders(w, bucket)
+
+			if !allowed {
+				limiter.response.rateLimitExceededResponse(w, r, retryAfter(bucket))
+				return
+			}
+		} else if bucket.Tokens() < 1 {
+			setRateLimitHeaders(w, bucket)
+			limiter.response.rateLimitExceededResponse(w, r, retryAfter(bucket))
+			return
+		}
+
+		if limiter.global != nil && !limiter.global.Allow() {
+			limiter.response.rateLimitExceededResponse(w, r, retryAfter(limiter.global))
+			return
+		}
+
+		if anonymous {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Rejected credentials are charged even when concurrent requests
+		// have emptied the bucket meanwhile, leaving it in debt.
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status == http.StatusUnauthorized {
+			bucket.Reserve()
+		}
+	})
+}
+
+// RateLimitUser rejects requests over the limit of their user with 429 Too
+// Many Requests and must run after Authenticate. Anonymous requests were
+// already limited by RateLimit and pass through. Every response carries the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers of the
+// user bucket.
+func (limiter *RateLimiter) RateLimitUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := contextGetUser(r)
+		if !limiter.config.Enabled || user.IsAnonymous() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bucket := limiter.client("user:" + strconv.FormatInt(user.ID, 10))
+
+		allowed := bucket.Allow()
+		setRateLimitHeaders(w, bucket)
+
+		if !allowed {
+			limiter.response.rateLimitExceededResponse(w, r, retryAfter(bucket))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryRateLimit is the gRPC counterpart of RateLimit and must run before
+// UnaryAuthenticate. Calls over the limit of their peer IP are rejected with
+// a ResourceExhausted status.
+func (limiter *RateLimiter) UnaryRateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	bucket, err := limiter.limitPeer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	if bucket != nil && status.Code(err) == codes.Unauthenticated {
+		bucket.Reserve()
+	}
+	return resp, err
+}
+
+// StreamRateLimit is the streaming counterpart of UnaryRateLimit.
+func (limiter *RateLimiter) StreamRateLimit(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	bucket, err := limiter.limitPeer(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	err = handler(srv, stream)
+	if bucket != nil && status.Code(err) == codes.Unauthenticated {
+		bucket.Reserve()
+	}
+	return err
+}
+
+// UnaryRateLimitUser is the gRPC counterpart of RateLimitUser and must run
+// after UnaryAuthenticate.
+func (limiter *RateLimiter) UnaryRateLimitUser(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := limiter.limitUser(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamRateLimitUser is the streaming counterpart of UnaryRateLimitUser.
+func (limiter *RateLimiter) StreamRateLimitUser(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := limiter.limitUser(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// limitPeer applies the rules of RateLimit to the peer of a call. When the
+// call carries credentials it returns the bucket of the peer IP, to be
+// charged if they are rejected.
+func (limiter *RateLimiter) limitPeer(ctx context.Context) (*rate.Limiter, error) {
+	if !limiter.config.Enabled {
+		return nil, nil
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "the server encountered a problem and could not process your request")
+	}
+
+	// Peers that are not on TCP, such as unix sockets, have no port.
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		ip = p.Addr.String()
+	}
+
+	bucket := limiter.client("ip:" + ip)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	anonymous := len(md.Get("authorization")) == 0
+	if anonymous {
+		if !bucket.Allow() {
+			return nil, rateLimitExceededStatus(ctx, bucket)
+		}
+	} else if bucket.Tokens() < 1 {
+		return nil, rateLimitExceededStatus(ctx, bucket)
+	}
+
+	if limiter.global != nil && !limiter.global.Allow() {
+		return nil, rateLimitExceededStatus(ctx, limiter.global)
+	}
+
+	if anonymous {
+		return nil, nil
+	}
+	return bucket, nil
+}
+
+// limitUser applies the rules of RateLimitUser to the user of a call.
+func (limiter *RateLimiter) limitUser(ctx context.Context) error {
+	user, ok := userFromContext(ctx)
+	if !limiter.config.Enabled || !ok || user.IsAnonymous() {
+		return nil
+	}
+
+	bucket := limiter.client("user:" + strconv.FormatInt(user.ID, 10))
+	if !bucket.Allow() {
+		return rateLimitExceededStatus(ctx, bucket)
+	}
+	return nil
+}
+
+// rateLimitExceededStatus sends the retry-after header of bucket and returns
+// a ResourceExhausted status.
+func rateLimitExceededStatus(ctx context.Context, bucket *rate.Limiter) error {
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter(bucket))))
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+// EvictStale periodically drops the buckets of idle clients until ctx is
+// cancelled.
+func (limiter *RateLimiter) EvictStale(ctx context.Context) {
+	ticker := time.NewTicker(limiterEvictInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		limiter.mu.Lock()
+		for key, client := range limiter.clients {
+			if time.Since(client.lastSeen) > limiterClientTTL {
+				delete(limiter.clients, key)
+			}
+		}
+		limiter.mu.Unlock()
+	}
+}
+
+func (limiter *RateLimiter) client(key string) *rate.Limiter {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	client, ok := limiter.clients[key]
+	if !ok {
+		client = &limiterClient{
+			limiter: rate.NewLimiter(rate.Limit(limiter.config.RPS), limiter.config.Burst),
+		}
+		limiter.clients[key] = client
+	}
+	client.lastSeen = time.Now()
+
+	return client.limiter
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, bucket *rate.Limiter) {
+	tokens := math.Max(bucket.Tokens(), 0)
+	missing := float64(bucket.Burst()) - tokens
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(bucket.Burst()))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(tokens))))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(secondsUntil(missing, bucket.Limit())))
+}
+
+// retryAfter returns the number of seconds until the bucket holds a token
+// again, at least one.
+func retryAfter(bucket *rate.Limiter) int {
+	seconds := secondsUntil(1-bucket.Tokens(), bucket.Limit())
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+func secondsUntil(tokens float64, limit rate.Limit) int {
+	if tokens <= 0 || limit <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / float64(limit)))
+}
@@ -2,11 +2,18 @@ package delivery
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/services/contact/internal/domain"
 )
 
+// statusClientClosedRequest is the non-standard status popularised by nginx
+// for requests the client abandoned before a response was written.
+const statusClientClosedRequest = 499
+
 // func logError(r *http.Request, err error) {
 // 	logger.PrintError(err, map[string]string{
 // 		"request_method": r.Method,
@@ -29,13 +36,13 @@ func writeJSON(w http.ResponseWriter, status int, data any, headers http.Header)
 		w.Header()[key] = value
 	}
 	w.Header().Set("Content-Type", "application/json")
-	// w.WriteHeader(status)
+	w.WriteHeader(status)
 	w.Write(js)
 	return nil
 }
 
 func (handler *responseHandler) logError(r *http.Request, err error) {
-	handler.logger.PrintError(err, map[string]string{
+	handler.logger.Ctx(r.Context()).PrintError(err, map[string]string{
 		"request_method": r.Method,
 		"request_url":    r.URL.String(),
 	})
@@ -49,7 +56,18 @@ func (handler *responseHandler) errorResponse(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// serverErrorResponse also handles the errors use cases return when the
+// request context ends, which are not server faults and are not logged.
 func (handler *responseHandler) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, domain.ErrCanceled):
+		handler.clientClosedRequestResponse(w, r)
+		return
+	case errors.Is(err, domain.ErrTimeout):
+		handler.timeoutResponse(w, r)
+		return
+	}
+
 	handler.logError(r, err)
 	message := "the server encountered a problem and could not process your request"
 	handler.errorResponse(w, r, http.StatusInternalServerError, message)
@@ -71,3 +89,59 @@ func (handler *responseHandler) editConflictResponse(w http.ResponseWriter, r *h
 	message := "unable to update the record due to an edit conflict, please try again"
 	handler.errorResponse(w, r, http.StatusConflict, message)
 }
+
+func (handler *responseHandler) duplicateMemberResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the contact is already a member of this group"
+	handler.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (handler *responseHandler) duplicateGroupNameResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a group with this name already exists"
+	handler.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (handler *responseHandler) groupNotEmptyResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the group still has members or subgroups, remove them first or delete it with policy=cascade"
+	handler.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (handler *responseHandler) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	handler.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (handler *responseHandler) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	handler.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (handler *responseHandler) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	handler.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (handler *responseHandler) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	handler.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (handler *responseHandler) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter int) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	message := "rate limit exceeded"
+	handler.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+func (handler *responseHandler) clientClosedRequestResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the request was canceled"
+	handler.errorResponse(w, r, statusClientClosedRequest, message)
+}
+
+func (handler *responseHandler) timeoutResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+
+	message := "the server took too long to process your request, please try again"
+	handler.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
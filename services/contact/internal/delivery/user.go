@@ -0,0 +1,138 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+
+	"advanced.microservices/pkg/helpers"
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/repository"
+	"github.com/julienschmidt/httprouter"
+)
+
+type UserHandler struct {
+	userUseCase domain.UserUseCase
+	response    responseHandler
+}
+
+func NewUserHandler(router *httprouter.Router, logger *jsonlog.Logger, userUseCase domain.UserUseCase) {
+	handler := &UserHandler{
+		userUseCase: userUseCase,
+		response:    responseHandler{logger: logger},
+	}
+	router.HandlerFunc(http.MethodPost, "/users", handler.register)
+	router.HandlerFunc(http.MethodPost, "/tokens/authentication", handler.createAuthenticationToken)
+	router.HandlerFunc(http.MethodDelete, "/tokens/authentication", handler.response.requireAuthenticatedUser(handler.deleteAuthenticationTokens))
+}
+
+func (handler *UserHandler) register(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := helpers.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := &domain.User{
+		Name:  input.Name,
+		Email: domain.NormalizeEmail(input.Email),
+	}
+
+	v := validator.New()
+
+	// The plaintext is checked before hashing, as bcrypt rejects passwords
+	// over 72 bytes.
+	if domain.ValidatePasswordPlaintext(v, input.Password); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if domain.ValidateUser(v, user); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.userUseCase.Register(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists")
+			handler.response.failedValidationResponse(w, r, v.Errors)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *UserHandler) createAuthenticationToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := helpers.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Email = domain.NormalizeEmail(input.Email)
+
+	v := validator.New()
+
+	domain.ValidateEmail(v, input.Email)
+	domain.ValidatePasswordPlaintext(v, input.Password)
+
+	if !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, err := handler.userUseCase.CreateAuthenticationToken(r.Context(), input.Email, input.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidCredentials):
+			handler.response.invalidCredentialsResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *UserHandler) deleteAuthenticationTokens(w http.ResponseWriter, r *http.Request) {
+	err := handler.userUseCase.DeleteAuthenticationTokens(r.Context(), contextGetUser(r).ID)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "authentication tokens successfully revoked"}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,252 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"advanced.microservices/pkg/helpers"
+	"advanced.microservices/pkg/jsonlog"
+	"advanced.microservices/pkg/validator"
+	"advanced.microservices/services/contact/internal/domain"
+	"advanced.microservices/services/contact/internal/repository"
+	"github.com/julienschmidt/httprouter"
+)
+
+type WebhookHandler struct {
+	webhookUseCase domain.WebhookUseCase
+	response       responseHandler
+}
+
+func NewWebhookHandler(router *httprouter.Router, logger *jsonlog.Logger, middleware *Middleware, webhookUseCase domain.WebhookUseCase) {
+	handler := &WebhookHandler{
+		webhookUseCase: webhookUseCase,
+		response:       responseHandler{logger: logger},
+	}
+	router.HandlerFunc(http.MethodGet, "/webhooks", middleware.requirePermission(domain.PermissionWebhooksAdmin, handler.list))
+	router.HandlerFunc(http.MethodPost, "/webhooks", middleware.requirePermission(domain.PermissionWebhooksAdmin, handler.create))
+	router.HandlerFunc(http.MethodGet, "/webhooks/:id", middleware.requirePermission(domain.PermissionWebhooksAdmin, handler.getById))
+	router.HandlerFunc(http.MethodPut, "/webhooks/:id", middleware.requirePermission(domain.PermissionWebhooksAdmin, handler.update))
+	router.HandlerFunc(http.MethodDelete, "/webhooks/:id", middleware.requirePermission(domain.PermissionWebhooksAdmin, handler.delete))
+	router.HandlerFunc(http.MethodGet, "/webhooks/:id/deliveries", middleware.requirePermission(domain.PermissionWebhooksAdmin, handler.listDeliveries))
+}
+
+func (handler *WebhookHandler) list(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := handler.webhookUseCase.List(r.Context(), contextGetUser(r).ID)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"webhooks": webhooks}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *WebhookHandler) create(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL    string             `json:"url"`
+		Events []domain.EventType `json:"events"`
+		Secret string             `json:"secret"`
+	}
+
+	err := helpers.ReadJSON(w, r, &input)
+
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhook := &domain.Webhook{
+		OwnerID: contextGetUser(r).ID,
+		URL:     input.URL,
+		Events:  append([]domain.EventType{}, input.Events...),
+		Secret:  input.Secret,
+	}
+
+	v := validator.New()
+
+	if domain.ValidateWebhook(v, webhook); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.webhookUseCase.Create(r.Context(), webhook)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/webhooks/%d", webhook.ID))
+
+	err = writeJSON(w, http.StatusCreated, envelope{"webhook": webhook}, headers)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *WebhookHandler) getById(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	webhook, err := handler.webhookUseCase.GetByID(r.Context(), id, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"webhook": webhook}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+// update edits a webhook. Setting active to true enables a disabled webhook
+// again and clears its failures.
+func (handler *WebhookHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	webhook, err := handler.webhookUseCase.GetByID(r.Context(), id, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		URL    *string             `json:"url"`
+		Events *[]domain.EventType `json:"events"`
+		Secret *string             `json:"secret"`
+		Active *bool               `json:"active"`
+	}
+
+	err = helpers.ReadJSON(w, r, &input)
+
+	if err != nil {
+		handler.response.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.URL != nil {
+		webhook.URL = *input.URL
+	}
+	if input.Events != nil {
+		webhook.Events = append([]domain.EventType{}, *input.Events...)
+	}
+	if input.Secret != nil {
+		webhook.Secret = *input.Secret
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+
+	v := validator.New()
+
+	if domain.ValidateWebhook(v, webhook); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.webhookUseCase.Update(r.Context(), webhook)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEditConflict):
+			handler.response.editConflictResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"webhook": webhook}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+func (handler *WebhookHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	err = handler.webhookUseCase.Delete(r.Context(), id, contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "webhook successfully deleted"}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
+
+// listDeliveries serves the delivery log of a webhook, newest delivery first
+// unless sorted by id. Each delivery lists its attempts with the response
+// status codes received.
+func (handler *WebhookHandler) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := helpers.ReadIDParam(r)
+	if err != nil || id < 1 {
+		handler.response.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		domain.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = helpers.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = helpers.ReadInt(qs, "page_size", 20, v)
+	input.Filters.Sort = helpers.ReadString(qs, "sort", "-id")
+	input.Filters.SortSafelist = domain.DeliverySortSafelist
+
+	if domain.ValidateFilters(v, input.Filters); !v.Valid() {
+		handler.response.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	deliveries, metadata, err := handler.webhookUseCase.ListDeliveries(r.Context(), id, contextGetUser(r).ID, input.Filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.response.notFoundResponse(w, r)
+		default:
+			handler.response.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"deliveries": deliveries, "metadata": metadata}, nil)
+	if err != nil {
+		handler.response.serverErrorResponse(w, r, err)
+	}
+}
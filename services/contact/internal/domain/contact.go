@@ -2,36 +2,251 @@ package domain
 
 import (
 	"context"
-	"regexp"
+	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"advanced.microservices/pkg/phone"
 	"advanced.microservices/pkg/validator"
 )
 
+// Contact is a person in the address book of its owner. Phone is the primary
+// number, used for searching and sorting; Phones lists any further numbers.
+// Numbers are kept as typed, next to their canonical E.164 form, and laid out
+// for the region of the reader by FormatPhones.
 type Contact struct {
-	ID        int64     `json:"id"`
-	FullName  string    `json:"full_name"`
-	Phone     string    `json:"phone"`
-	CreatedAt time.Time `json:"created_at"`
-	Version   int32     `json:"version"`
+	ID           int64             `json:"id"`
+	OwnerID      int64             `json:"-"`
+	FullName     string            `json:"full_name"`
+	Phone        string            `json:"phone"`
+	PhoneE164    string            `json:"phone_e164,omitempty"`
+	Phones       []PhoneNumber     `json:"phones,omitempty"`
+	Emails       []Email           `json:"emails,omitempty"`
+	Addresses    []Address         `json:"addresses,omitempty"`
+	Company      string            `json:"company,omitempty"`
+	Title        string            `json:"title,omitempty"`
+	Birthday     *Date             `json:"birthday,omitempty"`
+	Notes        string            `json:"notes,omitempty"`
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	DeletedAt    *time.Time        `json:"deleted_at,omitempty"`
+	Version      int32             `json:"version"`
 }
 
+type PhoneNumber struct {
+	Type   string `json:"type"`
+	Number string `json:"number"`
+	E164   string `json:"e164,omitempty"`
+}
+
+type Email struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+type Address struct {
+	Type       string `json:"type"`
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+var (
+	PhoneTypes   = []string{"mobile", "home", "work", "fax", "other"}
+	EmailTypes   = []string{"home", "work", "other"}
+	AddressTypes = []string{"home", "work", "other"}
+)
+
+// Limits on the size of a contact, so that a single record stays small
+// enough to be listed and exported in bulk.
+const (
+	maxContactEntries    = 10
+	maxCustomFields      = 50
+	maxCustomFieldKey    = 64
+	maxCustomFieldValue  = 1000
+	maxContactTextLength = 250
+	maxNotesLength       = 10000
+)
+
+// ContactSortSafelist lists the sort keys accepted when listing contacts.
+var ContactSortSafelist = []string{"id", "full_name", "phone", "created_at", "-id", "-full_name", "-phone", "-created_at"}
+
+// TrashSortSafelist lists the sort keys accepted when listing deleted
+// contacts.
+var TrashSortSafelist = []string{"id", "full_name", "deleted_at", "-id", "-full_name", "-deleted_at"}
+
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// ContactChange describes a contact that has just been created, updated or
+// deleted. Deleted contacts only carry their ID and owner.
+type ContactChange struct {
+	Type    ChangeType `json:"type"`
+	Contact Contact    `json:"contact"`
+}
+
+// Every ContactRepository and ContactUseCase method is scoped to the user
+// owning the contacts: records of other users are reported as not found.
+// Delete moves a contact to the trash, where it is hidden from every other
+// method but ListDeleted and Restore until it is purged. Revert sets the
+// editable fields of a live contact back to their value at an earlier
+// version, as a new version. Merge folds the duplicate into the contact with
+// MergeContacts, moves the group memberships of the duplicate over to the
+// contact and moves the duplicate to the trash, all at once.
 type ContactRepository interface {
-	Create(contact *Contact, ctx context.Context) error
-	GetByID(id int64, ctx context.Context) (*Contact, error)
-	Update(contact *Contact, ctx context.Context) error
-	Delete(id int64, ctx context.Context) error
+	Create(ctx context.Context, contact *Contact) error
+	CreateMany(ctx context.Context, contacts []*Contact) error
+	GetByID(ctx context.Context, id int64, ownerID int64) (*Contact, error)
+	Update(ctx context.Context, contact *Contact) error
+	Delete(ctx context.Context, id int64, ownerID int64) error
+	ListGroups(ctx context.Context, contactID int64, ownerID int64) ([]*Group, error)
+	List(ctx context.Context, ownerID int64, fullName string, phone string, filters Filters) ([]*Contact, Metadata, error)
+	Export(ctx context.Context, ownerID int64, groupID int64) (Cursor[Contact], error)
+	ListDeleted(ctx context.Context, ownerID int64, filters Filters) ([]*Contact, Metadata, error)
+	Restore(ctx context.Context, id int64, ownerID int64) (*Contact, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	Merge(ctx context.Context, id int64, duplicateID int64, ownerID int64) (*Contact, error)
+	ListMissingPhoneE164(ctx context.Context, afterID int64, limit int) ([]*Contact, error)
+	SetPhoneE164(ctx context.Context, contact *Contact) error
 }
 
 type ContactUseCase interface {
-	Create(contact *Contact) error
-	GetByID(id int64) (*Contact, error)
-	Update(contact *Contact) error
-	Delete(id int64) error
+	Create(ctx context.Context, contact *Contact) error
+	CreateMany(ctx context.Context, contacts []*Contact) error
+	GetByID(ctx context.Context, id int64, ownerID int64) (*Contact, error)
+	Update(ctx context.Context, contact *Contact) error
+	Delete(ctx context.Context, id int64, ownerID int64) error
+	ListGroups(ctx context.Context, contactID int64, ownerID int64) ([]*Group, error)
+	List(ctx context.Context, ownerID int64, fullName string, phone string, filters Filters) ([]*Contact, Metadata, error)
+	Watch(ctx context.Context) <-chan ContactChange
+	Export(ctx context.Context, ownerID int64, groupID int64) (Cursor[Contact], error)
+	ListDeleted(ctx context.Context, ownerID int64, filters Filters) ([]*Contact, Metadata, error)
+	Restore(ctx context.Context, id int64, ownerID int64) (*Contact, error)
+	PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error)
+	Revert(ctx context.Context, id int64, ownerID int64, version int32) (*Contact, error)
+	FindDuplicates(ctx context.Context, ownerID int64, threshold float64) ([]*DuplicateCluster, error)
+	Merge(ctx context.Context, id int64, duplicateID int64, ownerID int64) (*Contact, error)
+	BackfillPhoneE164(ctx context.Context, batch int) (int64, error)
 }
 
+// NormalizePhones parses the numbers of contact with parser and sets the
+// E.164 form of the valid ones, which is what a number is compared and shown
+// by. The numbers are kept as typed; the others are left without E.164 form
+// for ValidateContact to reject.
+func NormalizePhones(contact *Contact, parser *phone.Parser) {
+	contact.Phone, contact.PhoneE164 = normalizePhone(contact.Phone, parser)
+	for i := range contact.Phones {
+		contact.Phones[i].Number, contact.Phones[i].E164 = normalizePhone(contact.Phones[i].Number, parser)
+	}
+}
+
+func normalizePhone(raw string, parser *phone.Parser) (typed string, e164 string) {
+	typed = strings.TrimSpace(raw)
+	number, err := parser.Parse(typed)
+	if err != nil {
+		return typed, ""
+	}
+	return typed, number.E164()
+}
+
+// FormatPhones lays out the numbers of contact for display in region, from
+// their E.164 form. Numbers without one, stored before E.164 forms were kept,
+// are shown as typed. Phones is copied first, so that contacts sharing it,
+// such as those of the change feed, are left alone.
+func FormatPhones(contact *Contact, parser *phone.Parser, region string) {
+	contact.Phone = formatPhone(contact.Phone, contact.PhoneE164, parser, region)
+	if contact.Phones == nil {
+		return
+	}
+	contact.Phones = append([]PhoneNumber(nil), contact.Phones...)
+	for i := range contact.Phones {
+		contact.Phones[i].Number = formatPhone(contact.Phones[i].Number, contact.Phones[i].E164, parser, region)
+	}
+}
+
+func formatPhone(typed string, e164 string, parser *phone.Parser, region string) string {
+	if e164 == "" {
+		return typed
+	}
+	number, err := parser.Parse(e164)
+	if err != nil {
+		return typed
+	}
+	return parser.FormatIn(number, region)
+}
+
+// FillPhoneE164 sets the E.164 form of the numbers of contact that lack one,
+// as those stored before E.164 forms were kept, leaving the numbers as shown
+// alone. Numbers parser rejects are skipped. It reports whether any number
+// was filled in.
+func FillPhoneE164(contact *Contact, parser *phone.Parser) bool {
+	filled := false
+	fill := func(raw string, e164 *string) {
+		if *e164 != "" || raw == "" {
+			return
+		}
+		if number, err := parser.Parse(raw); err == nil {
+			*e164 = number.E164()
+			filled = true
+		}
+	}
+
+	fill(contact.Phone, &contact.PhoneE164)
+	for i := range contact.Phones {
+		fill(contact.Phones[i].Number, &contact.Phones[i].E164)
+	}
+	return filled
+}
+
+// ValidateContact checks contact, whose numbers must have been normalized by
+// NormalizePhones.
 func ValidateContact(v *validator.Validator, contact *Contact) {
 	v.Check(len(strings.Split(contact.FullName, " ")) == 3, "full name", "full name must contain 3 parts")
-	v.Check(validator.Matches(contact.Phone, regexp.MustCompile(`[0-9\[\]\(\\)\+\-]`)), "email", "must be a valid phone number")
+	v.Check(contact.PhoneE164 != "", "phone", "must be a valid phone number")
+
+	v.Check(len(contact.Phones) <= maxContactEntries, "phones", fmt.Sprintf("must not contain more than %d entries", maxContactEntries))
+	for i, phone := range contact.Phones {
+		key := fmt.Sprintf("phones[%d]", i)
+		v.Check(validator.PermittedValue(phone.Type, PhoneTypes...), key, "type must be one of "+strings.Join(PhoneTypes, ", "))
+		v.Check(phone.E164 != "", key, "must be a valid phone number")
+	}
+
+	v.Check(len(contact.Emails) <= maxContactEntries, "emails", fmt.Sprintf("must not contain more than %d entries", maxContactEntries))
+	for i, email := range contact.Emails {
+		key := fmt.Sprintf("emails[%d]", i)
+		v.Check(validator.PermittedValue(email.Type, EmailTypes...), key, "type must be one of "+strings.Join(EmailTypes, ", "))
+		v.Check(validator.Matches(email.Address, validator.EmailRX), key, "must be a valid email address")
+	}
+
+	v.Check(len(contact.Addresses) <= maxContactEntries, "addresses", fmt.Sprintf("must not contain more than %d entries", maxContactEntries))
+	for i, address := range contact.Addresses {
+		key := fmt.Sprintf("addresses[%d]", i)
+		v.Check(validator.PermittedValue(address.Type, AddressTypes...), key, "type must be one of "+strings.Join(AddressTypes, ", "))
+		v.Check(address.Street != "" || address.City != "" || address.Region != "" || address.PostalCode != "" || address.Country != "", key, "must not be empty")
+		for _, line := range []string{address.Street, address.City, address.Region, address.PostalCode, address.Country} {
+			v.Check(utf8.RuneCountInString(line) <= maxContactTextLength, key, fmt.Sprintf("lines must not be longer than %d characters", maxContactTextLength))
+		}
+	}
+
+	v.Check(utf8.RuneCountInString(contact.Company) <= maxContactTextLength, "company", fmt.Sprintf("must not be longer than %d characters", maxContactTextLength))
+	v.Check(utf8.RuneCountInString(contact.Title) <= maxContactTextLength, "title", fmt.Sprintf("must not be longer than %d characters", maxContactTextLength))
+	v.Check(utf8.RuneCountInString(contact.Notes) <= maxNotesLength, "notes", fmt.Sprintf("must not be longer than %d characters", maxNotesLength))
+
+	if contact.Birthday != nil {
+		v.Check(!contact.Birthday.After(DateOf(time.Now())), "birthday", "must not be in the future")
+	}
+
+	v.Check(len(contact.CustomFields) <= maxCustomFields, "custom fields", fmt.Sprintf("must not contain more than %d fields", maxCustomFields))
+	for key, value := range contact.CustomFields {
+		v.Check(key != "" && utf8.RuneCountInString(key) <= maxCustomFieldKey, "custom fields", fmt.Sprintf("names must be between 1 and %d characters long", maxCustomFieldKey))
+		v.Check(utf8.RuneCountInString(value) <= maxCustomFieldValue, "custom fields", fmt.Sprintf("values must not be longer than %d characters", maxCustomFieldValue))
+	}
 }
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+
+	"advanced.microservices/pkg/phone"
+)
+
+func TestNormalizeAndFormatPhones(t *testing.T) {
+	parser, err := phone.NewParser("US", phone.Plans)
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+
+	contact := &Contact{
+		Phone: " 415.555.2671 ",
+		Phones: []PhoneNumber{
+			{Type: "work", Number: "+44 20 7946 0958"},
+			{Type: "home", Number: "555"},
+		},
+	}
+
+	NormalizePhones(contact, parser)
+
+	if contact.Phone != "415.555.2671" || contact.PhoneE164 != "+14155552671" {
+		t.Errorf("NormalizePhones: phone %q (%q), want %q (%q)", contact.Phone, contact.PhoneE164, "415.555.2671", "+14155552671")
+	}
+	if contact.Phones[0].Number != "+44 20 7946 0958" || contact.Phones[0].E164 != "+442079460958" {
+		t.Errorf("NormalizePhones: phones[0] %q (%q), want the number as typed (%q)", contact.Phones[0].Number, contact.Phones[0].E164, "+442079460958")
+	}
+	if contact.Phones[1].E164 != "" {
+		t.Errorf("NormalizePhones: invalid number got E.164 form %q", contact.Phones[1].E164)
+	}
+
+	tests := []struct {
+		region string
+		phone  string
+		work   string
+	}{
+		{"US", "(415) 555-2671", "+44 20 7946 0958"},
+		{"GB", "+1 415-555-2671", "020 7946 0958"},
+	}
+
+	for _, tt := range tests {
+		formatted := *contact
+		FormatPhones(&formatted, parser, tt.region)
+
+		if formatted.Phone != tt.phone {
+			t.Errorf("FormatPhones(%s): phone %q, want %q", tt.region, formatted.Phone, tt.phone)
+		}
+		if formatted.Phones[0].Number != tt.work {
+			t.Errorf("FormatPhones(%s): phones[0] %q, want %q", tt.region, formatted.Phones[0].Number, tt.work)
+		}
+		if formatted.Phones[1].Number != "555" {
+			t.Errorf("FormatPhones(%s): number without E.164 form %q, want it as typed", tt.region, formatted.Phones[1].Number)
+		}
+	}
+
+	if contact.Phones[0].Number != "+44 20 7946 0958" {
+		t.Errorf("FormatPhones changed the phones of the original contact: %q", contact.Phones[0].Number)
+	}
+}
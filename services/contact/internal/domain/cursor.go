@@ -0,0 +1,10 @@
+package domain
+
+// Cursor streams records out of a repository one at a time so that large
+// result sets never have to be held in memory. It must always be closed.
+type Cursor[T any] interface {
+	Next() bool
+	Value() *T
+	Err() error
+	Close() error
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// dateLayout is the ISO 8601 calendar date format used by Date in JSON and
+// SQL.
+const dateLayout = "2006-01-02"
+
+// Date is a calendar date without time of day or time zone, such as a
+// birthday. It is rendered as "YYYY-MM-DD".
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseDate parses a "YYYY-MM-DD" date.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return DateOf(t), nil
+}
+
+// DateOf returns the date of t in t's location.
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+func (d Date) String() string {
+	return d.time().Format(dateLayout)
+}
+
+// After reports whether d is later than t.
+func (d Date) After(t Date) bool {
+	return d.time().After(t.time())
+}
+
+func (d Date) time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid date, expected a \"YYYY-MM-DD\" string")
+	}
+
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value implements driver.Valuer for date columns.
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan implements sql.Scanner for date columns.
+func (d *Date) Scan(src any) error {
+	switch src := src.(type) {
+	case time.Time:
+		*d = DateOf(src)
+		return nil
+	case []byte:
+		return d.Scan(string(src))
+	case string:
+		parsed, err := ParseDate(src)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into domain.Date", src)
+	}
+}
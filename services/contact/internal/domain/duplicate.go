@@ -0,0 +1,353 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultDuplicateThreshold is the name similarity from which two contacts
+// are reported as duplicates when no threshold is given.
+const DefaultDuplicateThreshold = 0.85
+
+// DuplicateCluster is a set of contacts that are likely the same person, with
+// the matches that brought them together. Two contacts match when they share
+// a phone number or their names are similar enough; a cluster also holds the
+// contacts matched only through another member.
+type DuplicateCluster struct {
+	Contacts []*Contact       `json:"contacts"`
+	Matches  []DuplicateMatch `json:"matches"`
+}
+
+// DuplicateMatch is a pair of matching contacts. NameSimilarity ranges from 0
+// to 1 and is reported even when the pair only matched on phone.
+type DuplicateMatch struct {
+	ContactIDs     [2]int64 `json:"contact_ids"`
+	SamePhone      bool     `json:"same_phone"`
+	NameSimilarity float64  `json:"name_similarity"`
+}
+
+// NormalizePhone reduces a phone number to its digits, so that numbers
+// differing only in formatting compare equal. Numbers without E.164 form,
+// which did not parse, are compared this way too.
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+// normalizeName lowercases the words of a full name and sorts them, so that
+// names differing only in case, spacing or word order compare equal.
+func normalizeName(fullName string) string {
+	words := strings.Fields(strings.ToLower(fullName))
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// NameSimilarity returns how alike two full names are, from 0 to 1: one minus
+// the edit distance between their normalized forms relative to the longer
+// one.
+func NameSimilarity(a string, b string) float64 {
+	return similarity([]rune(normalizeName(a)), []rune(normalizeName(b)))
+}
+
+func similarity(a []rune, b []rune) float64 {
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// levenshtein returns the number of single rune insertions, deletions and
+// substitutions turning a into b.
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, value := range values[1:] {
+		if value < m {
+			m = value
+		}
+	}
+	return m
+}
+
+// phoneKey returns the digits of the E.164 form of number, or of the number
+// itself when it has none.
+func phoneKey(number PhoneNumber) string {
+	if number.E164 != "" {
+		return NormalizePhone(number.E164)
+	}
+	return NormalizePhone(number.Number)
+}
+
+// contactPhones returns the distinct normalized numbers of contact.
+func contactPhones(contact *Contact) []string {
+	seen := make(map[string]bool)
+	phones := []string{}
+	for _, phone := range append([]PhoneNumber{{Number: contact.Phone, E164: contact.PhoneE164}}, contact.Phones...) {
+		normalized := phoneKey(phone)
+		if normalized != "" && !seen[normalized] {
+			seen[normalized] = true
+			phones = append(phones, normalized)
+		}
+	}
+	return phones
+}
+
+// FindDuplicates groups contacts into clusters of likely duplicates: contacts
+// sharing a normalized phone number, or whose NameSimilarity is at least
+// threshold. Contacts without duplicates are left out. Clusters and the
+// contacts within them are ordered by ID.
+func FindDuplicates(contacts []*Contact, threshold float64) []*DuplicateCluster {
+	sorted := append([]*Contact{}, contacts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	names := make([][]rune, len(sorted))
+	for i, contact := range sorted {
+		names[i] = []rune(normalizeName(contact.FullName))
+	}
+
+	// Pairs of matching contacts are keyed by their indices in sorted, the
+	// lower one first.
+	type pair struct{ i, j int }
+	ordered := func(i, j int) pair {
+		if i > j {
+			return pair{j, i}
+		}
+		return pair{i, j}
+	}
+
+	samePhone := make(map[pair]bool)
+	byPhone := make(map[string][]int)
+	for i, contact := range sorted {
+		for _, phone := range contactPhones(contact) {
+			for _, j := range byPhone[phone] {
+				samePhone[ordered(i, j)] = true
+			}
+			byPhone[phone] = append(byPhone[phone], i)
+		}
+	}
+
+	// Names are compared in order of length, so that the comparisons of a
+	// name stop at the first one too long to be similar enough: the edit
+	// distance is at least the difference in length.
+	byLength := make([]int, len(sorted))
+	for i := range byLength {
+		byLength[i] = i
+	}
+	sort.SliceStable(byLength, func(a, b int) bool {
+		return len(names[byLength[a]]) < len(names[byLength[b]])
+	})
+
+	similar := make(map[pair]bool)
+	for a, i := range byLength {
+		if len(names[i]) == 0 {
+			continue
+		}
+		for _, j := range byLength[a+1:] {
+			if 1-float64(len(names[j])-len(names[i]))/float64(len(names[j])) < threshold {
+				break
+			}
+			if similarity(names[i], names[j]) >= threshold {
+				similar[ordered(i, j)] = true
+			}
+		}
+	}
+
+	pairs := []pair{}
+	for p := range samePhone {
+		pairs = append(pairs, p)
+	}
+	for p := range similar {
+		if !samePhone[p] {
+			pairs = append(pairs, p)
+		}
+	}
+
+	parent := make([]int, len(sorted))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, p := range pairs {
+		if root, other := find(p.i), find(p.j); root != other {
+			if other < root {
+				root, other = other, root
+			}
+			parent[other] = root
+		}
+	}
+
+	clusters := []*DuplicateCluster{}
+	byRoot := make(map[int]*DuplicateCluster)
+	for i, contact := range sorted {
+		root := find(i)
+		cluster, ok := byRoot[root]
+		if !ok {
+			cluster = &DuplicateCluster{Contacts: []*Contact{}, Matches: []DuplicateMatch{}}
+			byRoot[root] = cluster
+			clusters = append(clusters, cluster)
+		}
+		cluster.Contacts = append(cluster.Contacts, contact)
+	}
+
+	for _, p := range pairs {
+		cluster := byRoot[find(p.i)]
+		cluster.Matches = append(cluster.Matches, DuplicateMatch{
+			ContactIDs:     [2]int64{sorted[p.i].ID, sorted[p.j].ID},
+			SamePhone:      samePhone[p],
+			NameSimilarity: similarity(names[p.i], names[p.j]),
+		})
+	}
+
+	duplicates := []*DuplicateCluster{}
+	for _, cluster := range clusters {
+		if len(cluster.Contacts) < 2 {
+			continue
+		}
+		sort.Slice(cluster.Matches, func(a, b int) bool {
+			x, y := cluster.Matches[a].ContactIDs, cluster.Matches[b].ContactIDs
+			return x[0] < y[0] || x[0] == y[0] && x[1] < y[1]
+		})
+		duplicates = append(duplicates, cluster)
+	}
+
+	return duplicates
+}
+
+// MergeContacts folds duplicate into contact field by field. Fields contact
+// has set are kept and the empty ones are taken from duplicate. Numbers,
+// email and postal addresses and custom fields of duplicate that contact
+// lacks are added, as long as the limits of ValidateContact allow; so are
+// its notes.
+func MergeContacts(contact *Contact, duplicate *Contact) {
+	if strings.TrimSpace(contact.FullName) == "" {
+		contact.FullName = duplicate.FullName
+	}
+
+	phones := make(map[string]bool)
+	for _, phone := range contactPhones(contact) {
+		phones[phone] = true
+	}
+	primary := PhoneNumber{Type: "other", Number: duplicate.Phone, E164: duplicate.PhoneE164}
+	if contact.Phone == "" {
+		contact.Phone = duplicate.Phone
+		contact.PhoneE164 = duplicate.PhoneE164
+		phones[phoneKey(primary)] = true
+	}
+	for _, phone := range append([]PhoneNumber{primary}, duplicate.Phones...) {
+		normalized := phoneKey(phone)
+		if normalized == "" || phones[normalized] || len(contact.Phones) >= maxContactEntries {
+			continue
+		}
+		phones[normalized] = true
+		contact.Phones = append(contact.Phones, phone)
+	}
+
+	for _, email := range duplicate.Emails {
+		if len(contact.Emails) >= maxContactEntries || hasEmail(contact.Emails, email.Address) {
+			continue
+		}
+		contact.Emails = append(contact.Emails, email)
+	}
+
+	for _, address := range duplicate.Addresses {
+		if len(contact.Addresses) >= maxContactEntries || hasAddress(contact.Addresses, address) {
+			continue
+		}
+		contact.Addresses = append(contact.Addresses, address)
+	}
+
+	if contact.Company == "" {
+		contact.Company = duplicate.Company
+	}
+	if contact.Title == "" {
+		contact.Title = duplicate.Title
+	}
+	if contact.Birthday == nil {
+		contact.Birthday = duplicate.Birthday
+	}
+
+	switch {
+	case contact.Notes == "":
+		contact.Notes = duplicate.Notes
+	case duplicate.Notes != "" && duplicate.Notes != contact.Notes:
+		notes := contact.Notes + "\n\n" + duplicate.Notes
+		if utf8.RuneCountInString(notes) <= maxNotesLength {
+			contact.Notes = notes
+		}
+	}
+
+	for key, value := range duplicate.CustomFields {
+		if _, ok := contact.CustomFields[key]; ok || len(contact.CustomFields) >= maxCustomFields {
+			continue
+		}
+		if contact.CustomFields == nil {
+			contact.CustomFields = make(map[string]string)
+		}
+		contact.CustomFields[key] = value
+	}
+}
+
+func hasEmail(emails []Email, address string) bool {
+	for _, email := range emails {
+		if strings.EqualFold(email.Address, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAddress(addresses []Address, address Address) bool {
+	key := func(address Address) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return unicode.ToLower(r)
+		}, address.Street+"|"+address.City+"|"+address.Region+"|"+address.PostalCode+"|"+address.Country)
+	}
+	for _, existing := range addresses {
+		if key(existing) == key(address) {
+			return true
+		}
+	}
+	return false
+}
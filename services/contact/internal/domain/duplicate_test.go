@@ -0,0 +1,397 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// clusterIDs returns the contact IDs of each cluster, in order.
+func clusterIDs(clusters []*DuplicateCluster) [][]int64 {
+	ids := [][]int64{}
+	for _, cluster := range clusters {
+		contactIDs := []int64{}
+		for _, contact := range cluster.Contacts {
+			contactIDs = append(contactIDs, contact.ID)
+		}
+		ids = append(ids, contactIDs)
+	}
+	return ids
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Ann Bee Cee", "Ann Bee Cee", 1},
+		{"Ann Bee Cee", "cee  ANN bee", 1},
+		{"Anna", "Anne", 0.75},
+		{"abc", "xyz", 0},
+		{"", "", 0},
+		{"Ann", "", 0},
+	}
+
+	for _, tt := range tests {
+		if got := NameSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("NameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		contacts  []*Contact
+		threshold float64
+		want      [][]int64
+	}{
+		{
+			name: "phone only",
+			contacts: []*Contact{
+				{ID: 1, FullName: "Ann Bee Cee", Phone: "(202) 555-0100", PhoneE164: "+12025550100"},
+				{ID: 2, FullName: "Xavier Yates Zimmer", Phone: "+1 202 555 0100", PhoneE164: "+12025550100"},
+				{ID: 3, FullName: "Dan Eve Fay", Phone: "(202) 555-0199", PhoneE164: "+12025550199"},
+			},
+			threshold: DefaultDuplicateThreshold,
+			want:      [][]int64{{1, 2}},
+		},
+		{
+			name: "phone without E.164 form",
+			contacts: []*Contact{
+				{ID: 1, FullName: "Ann Bee Cee", Phone: "(202) 555-0100", PhoneE164: "+12025550100"},
+				{ID: 2, FullName: "Xavier Yates Zimmer", Phone: "1-202-555-0100"},
+			},
+			threshold: DefaultDuplicateThreshold,
+			want:      [][]int64{{1, 2}},
+		},
+		{
+			name: "additional phone",
+			contacts: []*Contact{
+				{ID: 1, FullName: "Ann Bee Cee", Phone: "(202) 555-0100", PhoneE164: "+12025550100"},
+				{ID: 2, FullName: "Xavier Yates Zimmer", Phones: []PhoneNumber{{Type: "work", Number: "(202) 555-0100", E164: "+12025550100"}}},
+			},
+			threshold: DefaultDuplicateThreshold,
+			want:      [][]int64{{1, 2}},
+		},
+		{
+			name: "name order and case",
+			contacts: []*Contact{
+				{ID: 1, FullName: "Ann Bee Cee"},
+				{ID: 2, FullName: "cee ann BEE"},
+				{ID: 3, FullName: "Dan Eve Fay"},
+			},
+			threshold: DefaultDuplicateThreshold,
+			want:      [][]int64{{1, 2}},
+		},
+		{
+			name: "transitive",
+			contacts: []*Contact{
+				{ID: 3, FullName: "Ann Bee Ceel"},
+				{ID: 1, FullName: "Xavier Yates Zimmer", Phone: "(202) 555-0100", PhoneE164: "+12025550100"},
+				{ID: 2, FullName: "Ann Bee Cee", Phone: "202.555.0100", PhoneE164: "+12025550100"},
+			},
+			threshold: DefaultDuplicateThreshold,
+			want:      [][]int64{{1, 2, 3}},
+		},
+		{
+			name: "clusters ordered by ID",
+			contacts: []*Contact{
+				{ID: 4, FullName: "Dan Eve Fay"},
+				{ID: 2, FullName: "Ann Bee Cee"},
+				{ID: 3, FullName: "Dan Eve Fay"},
+				{ID: 1, FullName: "Ann Bee Cee"},
+				{ID: 5, FullName: "Gil Hal Ida"},
+			},
+			threshold: DefaultDuplicateThreshold,
+			want:      [][]int64{{1, 2}, {3, 4}},
+		},
+		{
+			name: "at threshold",
+			contacts: []*Contact{
+				{ID: 1, FullName: "Anna"},
+				{ID: 2, FullName: "Anne"},
+			},
+			threshold: 0.75,
+			want:      [][]int64{{1, 2}},
+		},
+		{
+			name: "above threshold",
+			contacts: []*Contact{
+				{ID: 1, FullName: "Anna"},
+				{ID: 2, FullName: "Anne"},
+			},
+			threshold: 0.76,
+			want:      [][]int64{},
+		},
+		{
+			name: "length difference at threshold",
+			contacts: []*Contact{
+				{ID: 1, FullName: "abcdefghi"},
+				{ID: 2, FullName: "abcdefghij"},
+			},
+			threshold: 0.9,
+			want:      [][]int64{{1, 2}},
+		},
+		{
+			name: "length difference above threshold",
+			contacts: []*Contact{
+				{ID: 1, FullName: "abcdefgh"},
+				{ID: 2, FullName: "abcdefghij"},
+			},
+			threshold: 0.9,
+			want:      [][]int64{},
+		},
+		{
+			// The comparisons of the short name stop at the long ones, which
+			// must still be compared with each other.
+			name: "early break",
+			contacts: []*Contact{
+				{ID: 1, FullName: "Al"},
+				{ID: 2, FullName: "Bartholomew Cornelius Dunsworth"},
+				{ID: 3, FullName: "Bartholomew Cornelius Dunswort"},
+				{ID: 4, FullName: "Ai"},
+			},
+			threshold: 0.5,
+			want:      [][]int64{{1, 4}, {2, 3}},
+		},
+		{
+			name: "empty names",
+			contacts: []*Contact{
+				{ID: 1, FullName: ""},
+				{ID: 2, FullName: "  "},
+			},
+			threshold: 0.5,
+			want:      [][]int64{},
+		},
+		{
+			name:      "no contacts",
+			contacts:  []*Contact{},
+			threshold: DefaultDuplicateThreshold,
+			want:      [][]int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterIDs(FindDuplicates(tt.contacts, tt.threshold))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindDuplicates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicatesMatches(t *testing.T) {
+	contacts := []*Contact{
+		{ID: 3, FullName: "Ann Bee Ceel"},
+		{ID: 1, FullName: "Xavier Yates Zimmer", Phone: "(202) 555-0100", PhoneE164: "+12025550100"},
+		{ID: 2, FullName: "Ann Bee Cee", Phone: "202.555.0100", PhoneE164: "+12025550100"},
+	}
+
+	clusters := FindDuplicates(contacts, DefaultDuplicateThreshold)
+	if len(clusters) != 1 {
+		t.Fatalf("FindDuplicates returned %d clusters, want 1", len(clusters))
+	}
+
+	want := []DuplicateMatch{
+		{ContactIDs: [2]int64{1, 2}, SamePhone: true, NameSimilarity: NameSimilarity("Xavier Yates Zimmer", "Ann Bee Cee")},
+		{ContactIDs: [2]int64{2, 3}, SamePhone: false, NameSimilarity: NameSimilarity("Ann Bee Cee", "Ann Bee Ceel")},
+	}
+	if got := clusters[0].Matches; !reflect.DeepEqual(got, want) {
+		t.Errorf("Matches = %+v, want %+v", got, want)
+	}
+}
+
+func TestFindDuplicatesKeepsInput(t *testing.T) {
+	contacts := []*Contact{{ID: 2, FullName: "Ann Bee Cee"}, {ID: 1, FullName: "Ann Bee Cee"}}
+
+	FindDuplicates(contacts, DefaultDuplicateThreshold)
+
+	if contacts[0].ID != 2 || contacts[1].ID != 1 {
+		t.Errorf("FindDuplicates reordered its input")
+	}
+}
+
+func phoneNumbers(n int, first int) []PhoneNumber {
+	phones := []PhoneNumber{}
+	for i := 0; i < n; i++ {
+		e164 := fmt.Sprintf("+1202555%04d", first+i)
+		phones = append(phones, PhoneNumber{Type: "other", Number: e164, E164: e164})
+	}
+	return phones
+}
+
+func TestMergeContactsFields(t *testing.T) {
+	birthday := &Date{}
+	contact := &Contact{ID: 1, FullName: "Ann Bee Cee", Company: "Acme"}
+	duplicate := &Contact{
+		ID:        2,
+		FullName:  "Cee Ann Bee",
+		Phone:     "(202) 555-0100",
+		PhoneE164: "+12025550100",
+		Phones:    []PhoneNumber{{Type: "work", Number: "202 555 0100", E164: "+12025550100"}},
+		Company:   "Other",
+		Title:     "Engineer",
+		Birthday:  birthday,
+	}
+
+	MergeContacts(contact, duplicate)
+
+	if contact.FullName != "Ann Bee Cee" || contact.Company != "Acme" {
+		t.Errorf("MergeContacts replaced set fields: %+v", contact)
+	}
+	if contact.Title != "Engineer" || contact.Birthday != birthday {
+		t.Errorf("MergeContacts did not fill empty fields: %+v", contact)
+	}
+	if contact.Phone != "(202) 555-0100" || contact.PhoneE164 != "+12025550100" {
+		t.Errorf("Phone = %q, %q, want the number of duplicate", contact.Phone, contact.PhoneE164)
+	}
+	if len(contact.Phones) != 0 {
+		t.Errorf("Phones = %+v, want none, as they repeat the primary number", contact.Phones)
+	}
+}
+
+func TestMergeContactsPhones(t *testing.T) {
+	contact := &Contact{ID: 1, FullName: "Ann Bee Cee", Phone: "(202) 555-0100", PhoneE164: "+12025550100"}
+	duplicate := &Contact{
+		ID:        2,
+		FullName:  "Ann Bee Cee",
+		Phone:     "(202) 555-0101",
+		PhoneE164: "+12025550101",
+		Phones: []PhoneNumber{
+			{Type: "home", Number: "1 202 555 0100"},
+			{Type: "work", Number: "(202) 555-0102", E164: "+12025550102"},
+		},
+	}
+
+	MergeContacts(contact, duplicate)
+
+	want := []PhoneNumber{
+		{Type: "other", Number: "(202) 555-0101", E164: "+12025550101"},
+		{Type: "work", Number: "(202) 555-0102", E164: "+12025550102"},
+	}
+	if !reflect.DeepEqual(contact.Phones, want) {
+		t.Errorf("Phones = %+v, want %+v", contact.Phones, want)
+	}
+	if contact.Phone != "(202) 555-0100" {
+		t.Errorf("Phone = %q, want it kept", contact.Phone)
+	}
+}
+
+func TestMergeContactsEntryLimits(t *testing.T) {
+	contact := &Contact{ID: 1, FullName: "Ann Bee Cee", Phone: "(202) 555-9999", PhoneE164: "+12025559999"}
+	contact.Phones = phoneNumbers(maxContactEntries-1, 1)
+	for i := 0; i < maxContactEntries; i++ {
+		contact.Emails = append(contact.Emails, Email{Type: "home", Address: fmt.Sprintf("ann%d@example.com", i)})
+	}
+	for i := 0; i < maxContactEntries-1; i++ {
+		contact.Addresses = append(contact.Addresses, Address{Type: "home", City: fmt.Sprintf("City %d", i)})
+	}
+
+	duplicate := &Contact{
+		ID:       2,
+		FullName: "Ann Bee Cee",
+		Phones:   phoneNumbers(3, 100),
+		Emails:   []Email{{Type: "work", Address: "ann@work.example.com"}},
+		Addresses: []Address{
+			{Type: "work", City: "city 0"},
+			{Type: "work", City: "Elsewhere"},
+			{Type: "work", City: "Nowhere"},
+		},
+	}
+
+	MergeContacts(contact, duplicate)
+
+	if len(contact.Phones) != maxContactEntries {
+		t.Errorf("len(Phones) = %d, want %d", len(contact.Phones), maxContactEntries)
+	}
+	if last := contact.Phones[len(contact.Phones)-1]; last != duplicate.Phones[0] {
+		t.Errorf("last phone = %+v, want the first new number of duplicate", last)
+	}
+	if len(contact.Emails) != maxContactEntries || hasEmail(contact.Emails, "ann@work.example.com") {
+		t.Errorf("Emails = %+v, want the %d of contact only", contact.Emails, maxContactEntries)
+	}
+	if len(contact.Addresses) != maxContactEntries {
+		t.Fatalf("len(Addresses) = %d, want %d", len(contact.Addresses), maxContactEntries)
+	}
+	if got := contact.Addresses[maxContactEntries-1].City; got != "Elsewhere" {
+		t.Errorf("added address = %q, want %q, skipping the one contact has", got, "Elsewhere")
+	}
+}
+
+func TestMergeContactsEmails(t *testing.T) {
+	contact := &Contact{ID: 1, Emails: []Email{{Type: "home", Address: "Ann@Example.com"}}}
+	duplicate := &Contact{ID: 2, Emails: []Email{
+		{Type: "work", Address: "ann@example.com"},
+		{Type: "work", Address: "ann@work.example.com"},
+	}}
+
+	MergeContacts(contact, duplicate)
+
+	want := []Email{
+		{Type: "home", Address: "Ann@Example.com"},
+		{Type: "work", Address: "ann@work.example.com"},
+	}
+	if !reflect.DeepEqual(contact.Emails, want) {
+		t.Errorf("Emails = %+v, want %+v", contact.Emails, want)
+	}
+}
+
+func TestMergeContactsNotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		contact   string
+		duplicate string
+		want      string
+	}{
+		{"taken", "", "Met at the fair.", "Met at the fair."},
+		{"kept", "Met at the fair.", "", "Met at the fair."},
+		{"same", "Met at the fair.", "Met at the fair.", "Met at the fair."},
+		{"concatenated", "Met at the fair.", "Likes tea.", "Met at the fair.\n\nLikes tea."},
+		{"at limit", strings.Repeat("a", maxNotesLength-4), "bb", strings.Repeat("a", maxNotesLength-4) + "\n\nbb"},
+		{"over limit", strings.Repeat("a", maxNotesLength-4), "bbb", strings.Repeat("a", maxNotesLength-4)},
+		{"over limit in runes", strings.Repeat("é", maxNotesLength-4), "ééé", strings.Repeat("é", maxNotesLength-4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact := &Contact{ID: 1, Notes: tt.contact}
+			MergeContacts(contact, &Contact{ID: 2, Notes: tt.duplicate})
+			if contact.Notes != tt.want {
+				t.Errorf("Notes = %.40q (%d bytes), want %.40q (%d bytes)", contact.Notes, len(contact.Notes), tt.want, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMergeContactsCustomFields(t *testing.T) {
+	contact := &Contact{ID: 1, CustomFields: map[string]string{}}
+	for i := 0; i < maxCustomFields-1; i++ {
+		contact.CustomFields[fmt.Sprintf("field%02d", i)] = "contact"
+	}
+	duplicate := &Contact{ID: 2, CustomFields: map[string]string{
+		"field00": "duplicate",
+		"extra1":  "duplicate",
+		"extra2":  "duplicate",
+	}}
+
+	MergeContacts(contact, duplicate)
+
+	if len(contact.CustomFields) != maxCustomFields {
+		t.Errorf("len(CustomFields) = %d, want %d", len(contact.CustomFields), maxCustomFields)
+	}
+	if got := contact.CustomFields["field00"]; got != "contact" {
+		t.Errorf(`CustomFields["field00"] = %q, want the value of contact`, got)
+	}
+}
+
+func TestMergeContactsCustomFieldsIntoNone(t *testing.T) {
+	contact := &Contact{ID: 1}
+	MergeContacts(contact, &Contact{ID: 2, CustomFields: map[string]string{"nickname": "Annie"}})
+
+	if got := contact.CustomFields["nickname"]; got != "Annie" {
+		t.Errorf(`CustomFields["nickname"] = %q, want %q`, got, "Annie")
+	}
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "errors"
+
+// ErrRecordNotFound and ErrEditConflict are returned by the repositories and
+// live here so that the use cases can match them without depending on the
+// repository package.
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrEditConflict   = errors.New("edit conflict")
+)
+
+// ErrCanceled and ErrTimeout are returned by the use cases when the context of
+// an operation ends before the operation does, so that callers can tell a
+// client that went away from a backend that is too slow.
+var (
+	ErrCanceled = errors.New("operation canceled")
+	ErrTimeout  = errors.New("operation timed out")
+)
+
+// ValidationError is returned by the use cases when the data an operation
+// derives, rather than takes from the caller, fails validation. Errors maps
+// fields to messages like validator.Validator does.
+type ValidationError struct {
+	Errors map[string]string
+}
+
+func (err *ValidationError) Error() string {
+	return "failed validation"
+}
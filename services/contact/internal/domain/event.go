@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventContactCreated     EventType = "contact.created"
+	EventContactUpdated     EventType = "contact.updated"
+	EventContactDeleted     EventType = "contact.deleted"
+	EventContactRestored    EventType = "contact.restored"
+	EventGroupRenamed       EventType = "group.renamed"
+	EventGroupDeleted       EventType = "group.deleted"
+	EventGroupMemberAdded   EventType = "group.member_added"
+	EventGroupMemberRemoved EventType = "group.member_removed"
+)
+
+// DomainEvent is the payload of an event. Each EventType has its own payload
+// type below.
+type DomainEvent interface {
+	EventType() EventType
+}
+
+type ContactCreated struct {
+	Contact Contact `json:"contact"`
+}
+
+type ContactUpdated struct {
+	Contact Contact `json:"contact"`
+}
+
+// ContactDeleted is emitted when a contact moves to the trash.
+type ContactDeleted struct {
+	ContactID int64 `json:"contact_id"`
+}
+
+// ContactRestored is emitted when a contact is brought back from the trash.
+type ContactRestored struct {
+	Contact Contact `json:"contact"`
+}
+
+type GroupRenamed struct {
+	GroupID int64  `json:"group_id"`
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+}
+
+// GroupDeleted is emitted for every group of a deleted subtree, after the
+// GroupMemberRemoved of its members.
+type GroupDeleted struct {
+	GroupID int64 `json:"group_id"`
+}
+
+type GroupMemberAdded struct {
+	GroupID   int64 `json:"group_id"`
+	ContactID int64 `json:"contact_id"`
+}
+
+type GroupMemberRemoved struct {
+	GroupID   int64 `json:"group_id"`
+	ContactID int64 `json:"contact_id"`
+}
+
+func (ContactCreated) EventType() EventType     { return EventContactCreated }
+func (ContactUpdated) EventType() EventType     { return EventContactUpdated }
+func (ContactDeleted) EventType() EventType     { return EventContactDeleted }
+func (ContactRestored) EventType() EventType    { return EventContactRestored }
+func (GroupRenamed) EventType() EventType       { return EventGroupRenamed }
+func (GroupDeleted) EventType() EventType       { return EventGroupDeleted }
+func (GroupMemberAdded) EventType() EventType   { return EventGroupMemberAdded }
+func (GroupMemberRemoved) EventType() EventType { return EventGroupMemberRemoved }
+
+// Event is a DomainEvent as stored in the outbox and handed to publishers.
+// IDs increase in the order the changes were committed, so consumers can use
+// them to drop the duplicates that at-least-once delivery implies.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      EventType       `json:"type"`
+	OwnerID   int64           `json:"owner_id"`
+	ActorID   *int64          `json:"actor_id"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NewEvent wraps payload into an event of ownerID, made by the actor of ctx.
+func NewEvent(ctx context.Context, ownerID int64, payload DomainEvent) (*Event, error) {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		Type:    payload.EventType(),
+		OwnerID: ownerID,
+		ActorID: ActorFromContext(ctx),
+		Payload: js,
+	}, nil
+}
+
+// Decode returns the typed payload of event.
+func (event *Event) Decode() (DomainEvent, error) {
+	var payload DomainEvent
+	switch event.Type {
+	case EventContactCreated:
+		payload = &ContactCreated{}
+	case EventContactUpdated:
+		payload = &ContactUpdated{}
+	case EventContactDeleted:
+		payload = &ContactDeleted{}
+	case EventContactRestored:
+		payload = &ContactRestored{}
+	case EventGroupRenamed:
+		payload = &GroupRenamed{}
+	case EventGroupDeleted:
+		payload = &GroupDeleted{}
+	case EventGroupMemberAdded:
+		payload = &GroupMemberAdded{}
+	case EventGroupMemberRemoved:
+		payload = &GroupMemberRemoved{}
+	default:
+		return nil, fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	if err := json.Unmarshal(event.Payload, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// The contact and group repositories write events to the outbox in the same
+// transaction as the change they describe, so that an event exists if and
+// only if its change was committed.
+type OutboxRepository interface {
+	// Dispatch hands the oldest due events, at most limit, to publish in
+	// order, and removes those it accepts from the outbox. An event publish
+	// rejects is postponed by policy.RetryDelay, or marked failed once it
+	// has been attempted policy.MaxAttempts times, and the rest of the batch
+	// is still handed out. Dispatch returns the number of events published
+	// and the first error met. An event whose removal fails after it was
+	// published is handed out again, so delivery is at least once.
+	Dispatch(ctx context.Context, limit int, policy OutboxPolicy, publish func(event *Event) error) (int, error)
+}
+
+// OutboxPolicy decides how events that fail to publish are retried. The
+// delay before attempt n+1 is Backoff doubled n-1 times, capped at
+// MaxBackoff. Failed events stay in the outbox but are no longer published.
+type OutboxPolicy struct {
+	MaxAttempts int32
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// RetryDelay returns the delay after the given failed attempt.
+func (policy OutboxPolicy) RetryDelay(attempt int32) time.Duration {
+	return retryDelay(policy.Backoff, policy.MaxBackoff, attempt)
+}
+
+// EventPublisher delivers events outside the service. Publish may be called
+// again with an event it already accepted.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+type OutboxUseCase interface {
+	Relay(ctx context.Context, limit int) (int, error)
+}
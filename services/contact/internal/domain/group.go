@@ -2,32 +2,105 @@ package domain
 
 import (
 	"context"
+	"strings"
 	"time"
+	"unicode"
 
 	"advanced.microservices/pkg/validator"
 )
 
+// Groups form a forest: a group without a parent is the root of a tree, such
+// as a company with departments and teams below it.
 type Group struct {
 	ID        int64     `json:"id"`
+	OwnerID   int64     `json:"-"`
+	ParentID  *int64    `json:"parent_id"`
 	GroupName string    `json:"group_name"`
 	CreatedAt time.Time `json:"created_at"`
 	Version   int32     `json:"version"`
 }
+
+type GroupMember struct {
+	GroupID   int64     `json:"group_id"`
+	ContactID int64     `json:"contact_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GroupDeletePolicy decides what happens to the members of a deleted group.
+type GroupDeletePolicy string
+
+const (
+	// GroupDeleteRestrict refuses to delete a group that still has members
+	// or subgroups.
+	GroupDeleteRestrict GroupDeletePolicy = "restrict"
+	// GroupDeleteCascade deletes the whole subtree of the group along with
+	// its memberships. The contacts themselves are kept.
+	GroupDeleteCascade GroupDeletePolicy = "cascade"
+)
+
+// Like contacts, groups are scoped to their owner. A contact can only be a
+// member of groups owned by the same user, and a group can only have a parent
+// owned by the same user. Group names are unique per owner, ignoring case.
+//
+// Move changes the parent of a group, carrying its subtree along; a nil
+// parent makes the group a root. Ancestors are listed nearest first and
+// descendants breadth first. ListMembers optionally includes the members of
+// every subgroup, each contact once.
 type GroupRepository interface {
-	Create(Group *Group, ctx context.Context) error
-	GetByID(id int64, ctx context.Context) (*Group, error)
-	Update(Group *Group, ctx context.Context) error
+	Create(ctx context.Context, Group *Group) error
+	GetByID(ctx context.Context, id int64, ownerID int64) (*Group, error)
+	Update(ctx context.Context, Group *Group) error
+	Delete(ctx context.Context, id int64, ownerID int64, policy GroupDeletePolicy) error
+	AddMember(ctx context.Context, member *GroupMember, ownerID int64) error
+	RemoveMember(ctx context.Context, groupID int64, contactID int64, ownerID int64) error
+	Move(ctx context.Context, id int64, parentID *int64, ownerID int64) (*Group, error)
+	Ancestors(ctx context.Context, id int64, ownerID int64) ([]*Group, error)
+	Descendants(ctx context.Context, id int64, ownerID int64) ([]*Group, error)
+	ListMembers(ctx context.Context, groupID int64, ownerID int64, includeSubgroups bool) ([]*Contact, error)
+	Export(ctx context.Context, ownerID int64) (Cursor[Group], error)
 }
 
 type GroupUseCase interface {
-	Create(Group *Group) error
-	GetByID(id int64) (*Group, error)
-	Update(Group *Group) error
+	Create(ctx context.Context, Group *Group) error
+	GetByID(ctx context.Context, id int64, ownerID int64) (*Group, error)
+	Update(ctx context.Context, Group *Group) error
+	Delete(ctx context.Context, id int64, ownerID int64, policy GroupDeletePolicy) error
+	AddMember(ctx context.Context, member *GroupMember, ownerID int64) error
+	RemoveMember(ctx context.Context, groupID int64, contactID int64, ownerID int64) error
+	Move(ctx context.Context, id int64, parentID *int64, ownerID int64) (*Group, error)
+	Ancestors(ctx context.Context, id int64, ownerID int64) ([]*Group, error)
+	Descendants(ctx context.Context, id int64, ownerID int64) ([]*Group, error)
+	ListMembers(ctx context.Context, groupID int64, ownerID int64, includeSubgroups bool) ([]*Contact, error)
+	Export(ctx context.Context, ownerID int64) (Cursor[Group], error)
 }
 
+// NormalizeGroupName trims a name and collapses inner runs of whitespace, so
+// that names only differing in spacing are treated as duplicates.
+func NormalizeGroupName(groupName string) string {
+	return strings.Join(strings.Fields(groupName), " ")
+}
+
+// ValidateGroupName expects a name already passed through NormalizeGroupName.
 func ValidateGroupName(v *validator.Validator, groupName string) {
+	v.Check(groupName != "", "group name", "must be provided")
+	v.Check(len(groupName) <= 250, "group name", "must not be longer than 250 bytes")
+	v.Check(strings.IndexFunc(groupName, unicode.IsControl) == -1, "group name", "must not contain control characters")
 }
 
 func ValidateGroup(v *validator.Validator, group *Group) {
-	v.Check(len(group.GroupName) <= 250, "group name", "must not be longer than 250 characters")
+	ValidateGroupName(v, group.GroupName)
+	ValidateGroupParent(v, group.ParentID)
+}
+
+func ValidateGroupParent(v *validator.Validator, parentID *int64) {
+	v.Check(parentID == nil || *parentID > 0, "parent id", "must be a positive integer")
+}
+
+func ValidateGroupDeletePolicy(v *validator.Validator, policy GroupDeletePolicy) {
+	v.Check(validator.PermittedValue(policy, GroupDeleteRestrict, GroupDeleteCascade), "policy", "must be restrict or cascade")
+}
+
+func ValidateGroupMember(v *validator.Validator, member *GroupMember) {
+	v.Check(member.GroupID > 0, "group id", "must be a positive integer")
+	v.Check(member.ContactID > 0, "contact id", "must be provided and be a positive integer")
 }
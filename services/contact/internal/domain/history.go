@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+type EntityType string
+
+const (
+	EntityContact EntityType = "contact"
+	EntityGroup   EntityType = "group"
+)
+
+type HistoryAction string
+
+const (
+	HistoryCreated  HistoryAction = "created"
+	HistoryUpdated  HistoryAction = "updated"
+	HistoryDeleted  HistoryAction = "deleted"
+	HistoryRestored HistoryAction = "restored"
+	HistoryMerged   HistoryAction = "merged"
+)
+
+// HistoryEntry records one change of a contact or group. Before and After are
+// JSON snapshots of the record as returned by the API; Before is null for a
+// creation and After for a permanent deletion. Version is the version of the
+// record after the change, so that the snapshot of any version is the After
+// of its entry. ActorID is nil for changes made by the service itself.
+type HistoryEntry struct {
+	ID            int64           `json:"id"`
+	EntityType    EntityType      `json:"entity_type"`
+	EntityID      int64           `json:"entity_id"`
+	OwnerID       int64           `json:"-"`
+	Version       int32           `json:"version"`
+	Action        HistoryAction   `json:"action"`
+	ActorID       *int64          `json:"actor_id"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	ChangedFields []string        `json:"changed_fields"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// FieldChange is a field that differs between two snapshots. From and To are
+// null where the field is absent.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// HistorySortSafelist lists the sort keys accepted when listing history.
+var HistorySortSafelist = []string{"version", "-version"}
+
+// The history is append-only and written by the contact and group
+// repositories in the same transaction as the change it records. Entries
+// outlive their record, so they stay readable after a group is deleted or a
+// contact purged.
+type HistoryRepository interface {
+	List(ctx context.Context, entityType EntityType, entityID int64, ownerID int64, filters Filters) ([]*HistoryEntry, Metadata, error)
+	GetVersion(ctx context.Context, entityType EntityType, entityID int64, ownerID int64, version int32) (*HistoryEntry, error)
+}
+
+type HistoryUseCase interface {
+	List(ctx context.Context, entityType EntityType, entityID int64, ownerID int64, filters Filters) ([]*HistoryEntry, Metadata, error)
+	Diff(ctx context.Context, entityType EntityType, entityID int64, ownerID int64, from int32, to int32) ([]FieldChange, error)
+}
+
+type actorContextKey struct{}
+
+// NewActorContext returns a copy of ctx recording userID as the user on whose
+// behalf changes are made, for the history.
+func NewActorContext(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, userID)
+}
+
+// ActorFromContext returns the user set by NewActorContext, or nil.
+func ActorFromContext(ctx context.Context) *int64 {
+	userID, ok := ctx.Value(actorContextKey{}).(int64)
+	if !ok {
+		return nil
+	}
+	return &userID
+}
+
+// NewHistoryEntry builds the entry recording a change from before to after,
+// either of which may be nil, made by the actor of ctx.
+func NewHistoryEntry(ctx context.Context, entityType EntityType, entityID int64, ownerID int64, version int32, action HistoryAction, before any, after any) (*HistoryEntry, error) {
+	entry := &HistoryEntry{
+		EntityType: entityType,
+		EntityID:   entityID,
+		OwnerID:    ownerID,
+		Version:    version,
+		Action:     action,
+		ActorID:    ActorFromContext(ctx),
+	}
+
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		return nil, err
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		return nil, err
+	}
+
+	changes, err := DiffSnapshots(entry.Before, entry.After)
+	if err != nil {
+		return nil, err
+	}
+	entry.ChangedFields = make([]string, 0, len(changes))
+	for _, change := range changes {
+		entry.ChangedFields = append(entry.ChangedFields, change.Field)
+	}
+
+	return entry, nil
+}
+
+// DiffSnapshots lists the top-level fields that differ between two
+// snapshots, sorted by name. The version field is ignored since it changes
+// every time.
+func DiffSnapshots(from json.RawMessage, to json.RawMessage) ([]FieldChange, error) {
+	var fromFields, toFields map[string]json.RawMessage
+	if len(from) > 0 {
+		if err := json.Unmarshal(from, &fromFields); err != nil {
+			return nil, err
+		}
+	}
+	if len(to) > 0 {
+		if err := json.Unmarshal(to, &toFields); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make(map[string]bool)
+	for name := range fromFields {
+		names[name] = true
+	}
+	for name := range toFields {
+		names[name] = true
+	}
+	delete(names, "version")
+
+	changes := []FieldChange{}
+	for name := range names {
+		fromValue, toValue := fromFields[name], toFields[name]
+		if bytes.Equal(fromValue, toValue) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, From: nullJSON(fromValue), To: nullJSON(toValue)})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+func snapshot(record any) (json.RawMessage, error) {
+	if record == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(record)
+	if err != nil || bytes.Equal(js, []byte("null")) {
+		return nil, err
+	}
+	return js, nil
+}
+
+func nullJSON(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "context"
+
+const (
+	PermissionContactsRead  = "contacts:read"
+	PermissionContactsWrite = "contacts:write"
+	PermissionGroupsAdmin   = "groups:admin"
+	// PermissionWebhooksAdmin guards webhooks, which make the service send
+	// requests on a user's behalf. It is not part of DefaultPermissions and
+	// has to be granted by an operator.
+	PermissionWebhooksAdmin = "webhooks:admin"
+	// PermissionLogsAdmin guards runtime changes to the service itself. It is
+	// not part of DefaultPermissions and has to be granted by an operator.
+	PermissionLogsAdmin = "logs:admin"
+)
+
+// DefaultPermissions are granted to every newly registered user.
+var DefaultPermissions = Permissions{
+	PermissionContactsRead,
+	PermissionContactsWrite,
+	PermissionGroupsAdmin,
+}
+
+type Permissions []string
+
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] {
+			return true
+		}
+	}
+	return false
+}
+
+type PermissionRepository interface {
+	GetAllForUser(ctx context.Context, userID int64) (Permissions, error)
+}
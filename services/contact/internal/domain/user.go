@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"advanced.microservices/pkg/validator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	AnonymousUser         = &User{}
+	ErrInvalidCredentials = errors.New("invalid authentication credentials")
+)
+
+type User struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Password  password  `json:"-"`
+	Version   int32     `json:"-"`
+}
+
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
+type password struct {
+	plaintext *string
+	hash      []byte
+}
+
+func (p *password) Set(plaintextPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	if err != nil {
+		return err
+	}
+	p.plaintext = &plaintextPassword
+	p.hash = hash
+	return nil
+}
+
+// SetHash loads an already hashed password, as read from storage.
+func (p *password) SetHash(hash []byte) {
+	p.plaintext = nil
+	p.hash = hash
+}
+
+func (p *password) Hash() []byte {
+	return p.hash
+}
+
+func (p *password) Matches(plaintextPassword string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+type UserRepository interface {
+	// Create inserts user and grants it permissions as a single operation, so
+	// that a failed grant cannot leave a user without permissions behind.
+	Create(ctx context.Context, user *User, permissions Permissions) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetForToken(ctx context.Context, scope string, tokenPlaintext string) (*User, error)
+}
+
+type UserUseCase interface {
+	Register(ctx context.Context, user *User) error
+	CreateAuthenticationToken(ctx context.Context, email string, password string) (*Token, error)
+	DeleteAuthenticationTokens(ctx context.Context, userID int64) error
+	GetForToken(ctx context.Context, scope string, tokenPlaintext string) (*User, error)
+	GetPermissions(ctx context.Context, userID int64) (Permissions, error)
+}
+
+// NormalizeEmail lower-cases an address so that uniqueness is case-insensitive.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func ValidateEmail(v *validator.Validator, email string) {
+	v.Check(email != "", "email", "must be provided")
+	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+}
+
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+}
+
+func ValidateUser(v *validator.Validator, user *User) {
+	v.Check(user.Name != "", "name", "must be provided")
+	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	ValidateEmail(v, user.Email)
+
+	if user.Password.plaintext != nil {
+		ValidatePasswordPlaintext(v, *user.Password.plaintext)
+	}
+
+	if user.Password.hash == nil {
+		panic("missing password hash for user")
+	}
+}
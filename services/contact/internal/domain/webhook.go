@@ -0,0 +1,246 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"advanced.microservices/pkg/validator"
+)
+
+// Webhook subscribes a URL to the events of its owner. An empty Events
+// subscribes to every event type. Secret keys the signature of the payloads
+// and is never sent back. A webhook is disabled once enough deliveries in a
+// row have failed; Failures counts them.
+type Webhook struct {
+	ID         int64       `json:"id"`
+	OwnerID    int64       `json:"-"`
+	URL        string      `json:"url"`
+	Events     []EventType `json:"events"`
+	Secret     string      `json:"-"`
+	Active     bool        `json:"active"`
+	Failures   int32       `json:"failures"`
+	DisabledAt *time.Time  `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Version    int32       `json:"version"`
+}
+
+// Subscribed reports whether webhook wants events of eventType.
+func (webhook *Webhook) Subscribed(eventType EventType) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range webhook.Events {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event to be sent to a webhook. It stays pending,
+// with attempts scheduled at NextAttemptAt, until it succeeds or runs out of
+// attempts; from then on NextAttemptAt is when the last attempt was made.
+// Payload is the request body.
+type WebhookDelivery struct {
+	ID            int64            `json:"id"`
+	WebhookID     int64            `json:"webhook_id"`
+	EventID       int64            `json:"event_id"`
+	EventType     EventType        `json:"event_type"`
+	Payload       json.RawMessage  `json:"-"`
+	Status        DeliveryStatus   `json:"status"`
+	Attempts      int32            `json:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Log           []WebhookAttempt `json:"log"`
+}
+
+// WebhookAttempt records one request of a delivery. StatusCode is nil when
+// no response was received, in which case Error says why.
+type WebhookAttempt struct {
+	Attempt    int32     `json:"attempt"`
+	StatusCode *int      `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookJob is a claimed delivery together with the webhook it goes to.
+type WebhookJob struct {
+	Delivery WebhookDelivery
+	Webhook  Webhook
+}
+
+// WebhookPolicy decides how deliveries are made. Each request may take up to
+// Timeout. The delay before attempt n+1 is Backoff doubled n-1 times, capped
+// at MaxBackoff. A webhook is disabled after DisableAfter failed deliveries
+// in a row.
+type WebhookPolicy struct {
+	Timeout      time.Duration
+	MaxAttempts  int32
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	DisableAfter int32
+}
+
+// RetryDelay returns the delay after the given failed attempt.
+func (policy WebhookPolicy) RetryDelay(attempt int32) time.Duration {
+	return retryDelay(policy.Backoff, policy.MaxBackoff, attempt)
+}
+
+// retryDelay doubles backoff once per failed attempt after the first, up to
+// maxBackoff.
+func retryDelay(backoff time.Duration, maxBackoff time.Duration, attempt int32) time.Duration {
+	delay := backoff
+	for i := int32(1); i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// The webhooks of an owner are scoped like contacts: webhooks of other users
+// are reported as not found.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *Webhook) error
+	GetByID(ctx context.Context, id int64, ownerID int64) (*Webhook, error)
+	List(ctx context.Context, ownerID int64) ([]*Webhook, error)
+	Update(ctx context.Context, webhook *Webhook) error
+	Delete(ctx context.Context, id int64, ownerID int64) error
+	ListDeliveries(ctx context.Context, webhookID int64, ownerID int64, filters Filters) ([]*WebhookDelivery, Metadata, error)
+	// Enqueue creates a pending delivery of event for every active webhook
+	// of its owner subscribed to its type. Enqueueing an event again has no
+	// effect.
+	Enqueue(ctx context.Context, event *Event) error
+	// Claim returns up to limit pending deliveries that are due, of active
+	// webhooks, and postpones them by lease so that no other worker claims
+	// them meanwhile. A delivery whose worker dies is claimed again once the
+	// lease expires.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*WebhookJob, error)
+	// Complete records attempt of delivery, whose Status, Attempts and
+	// NextAttemptAt must already be updated. A succeeded delivery resets the
+	// failures of the webhook and a failed one increments them, disabling
+	// the webhook when they reach disableAfter.
+	Complete(ctx context.Context, delivery *WebhookDelivery, attempt *WebhookAttempt, disableAfter int32) error
+}
+
+type WebhookUseCase interface {
+	Create(ctx context.Context, webhook *Webhook) error
+	GetByID(ctx context.Context, id int64, ownerID int64) (*Webhook, error)
+	List(ctx context.Context, ownerID int64) ([]*Webhook, error)
+	Update(ctx context.Context, webhook *Webhook) error
+	Delete(ctx context.Context, id int64, ownerID int64) error
+	ListDeliveries(ctx context.Context, webhookID int64, ownerID int64, filters Filters) ([]*WebhookDelivery, Metadata, error)
+	Enqueue(ctx context.Context, event *Event) error
+	Deliver(ctx context.Context, limit int) (int, error)
+}
+
+// WebhookSender makes the request of a delivery attempt. It returns the
+// response status code, or an error when no response was received.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *Webhook, delivery *WebhookDelivery) (int, error)
+}
+
+// EventTypes lists every event type a webhook can subscribe to.
+var EventTypes = []EventType{
+	EventContactCreated,
+	EventContactUpdated,
+	EventContactDeleted,
+	EventContactRestored,
+	EventGroupRenamed,
+	EventGroupDeleted,
+	EventGroupMemberAdded,
+	EventGroupMemberRemoved,
+}
+
+// DeliverySortSafelist lists the sort keys accepted when listing deliveries.
+var DeliverySortSafelist = []string{"id", "-id"}
+
+const (
+	maxWebhookURLLength = 2048
+	minWebhookSecret    = 16
+	maxWebhookSecret    = 256
+)
+
+func ValidateWebhook(v *validator.Validator, webhook *Webhook) {
+	v.Check(webhook.URL != "", "url", "must be provided")
+	v.Check(len(webhook.URL) <= maxWebhookURLLength, "url", "must not be more than 2048 bytes long")
+	u, err := url.Parse(webhook.URL)
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil, "url", "must be an absolute http or https URL without credentials")
+	if err == nil {
+		ip := net.ParseIP(u.Hostname())
+		v.Check(ip == nil || PublicIP(ip), "url", "must not point to a private or local address")
+	}
+
+	v.Check(utf8.RuneCountInString(webhook.Secret) >= minWebhookSecret, "secret", "must be at least 16 characters long")
+	v.Check(len(webhook.Secret) <= maxWebhookSecret, "secret", "must not be more than 256 bytes long")
+
+	seen := make(map[EventType]bool, len(webhook.Events))
+	for _, eventType := range webhook.Events {
+		v.Check(permittedEventType(eventType), "events", "must only contain known event types")
+		v.Check(!seen[eventType], "events", "must not contain duplicate values")
+		seen[eventType] = true
+	}
+}
+
+// reservedNetworks are the non-public ranges the net.IP predicates do not
+// cover: "this network", carrier-grade NAT, benchmarking and the NAT64
+// prefixes, which reach IPv4 addresses through a local translator.
+var reservedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"198.18.0.0/15",
+	"64:ff9b::/96",
+	"64:ff9b:1::/48",
+)
+
+// PublicIP reports whether webhooks may be sent to ip: loopback, private,
+// link-local, multicast, unspecified and reserved addresses would let a
+// webhook reach the network of the service, such as cloud metadata endpoints.
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func permittedEventType(eventType EventType) bool {
+	for _, permitted := range EventTypes {
+		if eventType == permitted {
+			return true
+		}
+	}
+	return false
+}
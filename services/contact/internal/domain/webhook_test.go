@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"100.63.255.255", true},
+		{"100.128.0.0", true},
+		{"198.17.255.255", true},
+		{"198.20.0.0", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"0.1.2.3", false},
+		{"0.255.255.255", false},
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"64:ff9b::7f00:1", false},
+		{"64:ff9b::808:808", false},
+		{"64:ff9b:1::a00:1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:100.64.0.1", false},
+		{"::ffff:8.8.8.8", true},
+	}
+
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("invalid test address %q", tt.ip)
+		}
+		if got := PublicIP(ip); got != tt.want {
+			t.Errorf("PublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookPolicyRetryDelay(t *testing.T) {
+	policy := WebhookPolicy{Backoff: 5 * time.Second, MaxBackoff: time.Minute}
+
+	tests := []struct {
+		attempt int32
+		want    time.Duration
+	}{
+		{0, 5 * time.Second},
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{4, 40 * time.Second},
+		{5, time.Minute},
+		{6, time.Minute},
+		{1000, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.RetryDelay(tt.attempt); got != tt.want {
+			t.Errorf("RetryDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// Handler consumes an event. Returning an error makes the relay retry the
+// event later, with every handler of the bus.
+type Handler func(ctx context.Context, event *domain.Event) error
+
+// Bus is an in-process domain.EventPublisher: it hands every event to the
+// subscribed handlers, in subscription order. With the relay, handlers see
+// each event at least once, so they must be idempotent.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds handler to the bus.
+func (bus *Bus) Subscribe(handler Handler) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.handlers = append(bus.handlers, handler)
+}
+
+// Publish implements domain.EventPublisher. It stops at the first handler
+// that fails.
+func (bus *Bus) Publish(ctx context.Context, event *domain.Event) error {
+	bus.mu.RLock()
+	handlers := bus.handlers
+	bus.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// File is a domain.EventPublisher appending events as newline-delimited JSON
+// to a file, for local runs.
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFile opens path for appending, creating it if needed.
+func NewFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{file: file}, nil
+}
+
+// Publish implements domain.EventPublisher. Each event is written with a
+// single call, so that lines stay whole.
+func (publisher *File) Publish(ctx context.Context, event *domain.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	_, err = publisher.file.Write(append(js, '\n'))
+	return err
+}
+
+func (publisher *File) Close() error {
+	return publisher.file.Close()
+}
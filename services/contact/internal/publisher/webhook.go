@@ -0,0 +1,120 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// maxWebhookResponse bounds how much of a response body is read, so that
+// the connection can be reused without trusting the receiver.
+const maxWebhookResponse = 64 << 10
+
+// ErrForbiddenAddress is returned by Send when the URL of a webhook resolves
+// to an address domain.PublicIP rejects.
+var ErrForbiddenAddress = errors.New("publisher: webhook address is not public")
+
+// WebhookSender is the domain.WebhookSender posting deliveries over HTTP.
+// Redirects are not followed: the receiver must answer with a 2xx status.
+// Requests are bounded by the context passed to Send. Connections are only
+// made to public addresses, checked once the host name has been resolved so
+// that DNS cannot point a webhook back into the network of the service; for
+// the same reason no proxy is used.
+type WebhookSender struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewWebhookSender(version string) *WebhookSender {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   controlPublicAddress,
+	}
+
+	return &WebhookSender{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext:           dialer.DialContext,
+				ForceAttemptHTTP2:     true,
+				MaxIdleConns:          100,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		userAgent: "contact-service-webhooks/" + version,
+	}
+}
+
+// Send implements domain.WebhookSender. The payload is signed with the
+// secret of the webhook, see Sign.
+func (sender *WebhookSender) Send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", sender.userAgent)
+	req.Header.Set("X-Webhook-Id", strconv.FormatInt(webhook.ID, 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Event", string(delivery.EventType))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := sender.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxWebhookResponse))
+
+	return res.StatusCode, nil
+}
+
+// controlPublicAddress is the net.Dialer Control rejecting connections to the
+// addresses domain.PublicIP rejects. It runs on the resolved address, just
+// before connecting.
+func controlPublicAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !domain.PublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// Sign returns the X-Webhook-Signature of a payload sent at timestamp:
+// "sha256=" followed by the hex encoded HMAC-SHA256, keyed by secret, of the
+// timestamp, a dot and the payload. Receivers recompute it to authenticate
+// the request, and reject old timestamps to stop replays.
+func Sign(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
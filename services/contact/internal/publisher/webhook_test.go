@@ -0,0 +1,25 @@
+package publisher
+
+import "testing"
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		secret    string
+		timestamp string
+		payload   string
+		want      string
+	}{
+		{"0123456789abcdef", "1700000000", `{"id":1}`, "sha256=4bcaced68dfea90a68df035b89cb7fb26692d899d32a1ccb1b0616cf48e4d1ed"},
+		{"0123456789abcdef", "1700000001", `{"id":1}`, "sha256=c3bde3c3645f35c5bad9f7d0ac0ece4b0b2703b3e1b4f20e0162e18c73c6d5ba"},
+	}
+
+	for _, tt := range tests {
+		if got := Sign(tt.secret, tt.timestamp, []byte(tt.payload)); got != tt.want {
+			t.Errorf("Sign(%q, %q, %q) = %q, want %q", tt.secret, tt.timestamp, tt.payload, got, tt.want)
+		}
+	}
+
+	if Sign("0123456789abcdef", "1700000000", []byte(`{"id":1}`)) == Sign("fedcba9876543210", "1700000000", []byte(`{"id":1}`)) {
+		t.Error("Sign gives the same signature for different secrets")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"advanced.microservices/services/contact/internal/domain"
 )
@@ -13,64 +16,134 @@ type SQLContactRepository struct {
 }
 
 // Create implements domain.ContactRepository
-func (repository *SQLContactRepository) Create(contact *domain.Contact, ctx context.Context) error {
+func (repository *SQLContactRepository) Create(ctx context.Context, contact *domain.Contact) error {
 	query := `
-		INSERT INTO contacts (full_name, phone)
-		VALUES ($1, $2)
-		RETURNING id, created_at`
-	args := []any{contact.FullName, contact.Phone}
-	err := repository.DB.QueryRowContext(ctx, query, args...).Scan(&contact.ID, &contact.CreatedAt)
+		INSERT INTO contacts (owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		                      company, title, birthday, notes, custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, version`
+	tx, err := repository.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	args := contactArgs(contact)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&contact.ID, &contact.CreatedAt, &contact.Version)
+	if err != nil {
+		return err
+	}
+
+	err = recordContactHistory(ctx, tx, domain.HistoryCreated, nil, contact)
+	if err != nil {
+		return err
+	}
+
+	err = recordEvent(ctx, tx, contact.OwnerID, domain.ContactCreated{Contact: *contact})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateMany implements domain.ContactRepository. All contacts are inserted in
+// a single transaction: either every contact is stored or none is.
+func (repository *SQLContactRepository) CreateMany(ctx context.Context, contacts []*domain.Contact) error {
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO contacts (owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		                      company, title, birthday, notes, custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, version`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, contact := range contacts {
+		err := stmt.QueryRowContext(ctx, contactArgs(contact)...).Scan(&contact.ID, &contact.CreatedAt, &contact.Version)
+		if err != nil {
+			return err
+		}
+
+		err = recordContactHistory(ctx, tx, domain.HistoryCreated, nil, contact)
+		if err != nil {
+			return err
+		}
+
+		err = recordEvent(ctx, tx, contact.OwnerID, domain.ContactCreated{Contact: *contact})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// Delete implements domain.ContactRepository
-func (repository *SQLContactRepository) Delete(id int64, ctx context.Context) error {
+// Delete implements domain.ContactRepository. The contact only moves to the
+// trash: its memberships are kept so that Restore can bring them back.
+func (repository *SQLContactRepository) Delete(ctx context.Context, id int64, ownerID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getContactForUpdate(ctx, tx, id, ownerID, false)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		DELETE FROM contacts
-		WHERE id = $1`
+		UPDATE contacts
+		SET deleted_at = NOW(), version = version + 1
+		WHERE id = $1
+		RETURNING deleted_at, version`
 
-	result, err := repository.DB.ExecContext(ctx, query, id)
+	after := *before
+	err = tx.QueryRowContext(ctx, query, id).Scan(&after.DeletedAt, &after.Version)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	err = recordContactHistory(ctx, tx, domain.HistoryDeleted, before, &after)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+
+	err = recordEvent(ctx, tx, ownerID, domain.ContactDeleted{ContactID: id})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // GetByID implements domain.ContactRepository
-func (repository *SQLContactRepository) GetByID(id int64, ctx context.Context) (*domain.Contact, error) {
+func (repository *SQLContactRepository) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Contact, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, full_name, phone, version
+		SELECT id, owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		       company, title, birthday, notes, custom_fields, created_at, deleted_at, version
 		FROM contacts
-		WHERE id = $1`
+		WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL`
 
 	var contact domain.Contact
 
-	err := repository.DB.QueryRowContext(ctx, query, id).Scan(
-		&contact.ID,
-		&contact.FullName,
-		&contact.Phone,
-		&contact.Version,
-	)
+	err := repository.DB.QueryRowContext(ctx, query, id, ownerID).Scan(contactFields(&contact)...)
 
 	if err != nil {
 		switch {
@@ -85,21 +158,37 @@ func (repository *SQLContactRepository) GetByID(id int64, ctx context.Context) (
 }
 
 // Update implements domain.ContactRepository
-func (repository *SQLContactRepository) Update(contact *domain.Contact, ctx context.Context) error {
+func (repository *SQLContactRepository) Update(ctx context.Context, contact *domain.Contact) error {
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getContactForUpdate(ctx, tx, contact.ID, contact.OwnerID, false)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	if before.Version != contact.Version {
+		return ErrEditConflict
+	}
+
 	query := `
 		UPDATE contacts
-		SET full_name = $1, phone = $2, version = version + 1
-		WHERE id = $3 AND version = $4
+		SET full_name = $2, phone = $3, phone_e164 = $4, phones = $5, emails = $6, addresses = $7,
+		    company = $8, title = $9, birthday = $10, notes = $11, custom_fields = $12,
+		    version = version + 1
+		WHERE owner_id = $1 AND id = $13 AND version = $14 AND deleted_at IS NULL
 		RETURNING version`
 
-	args := []any{
-		contact.FullName,
-		contact.Phone,
-		contact.ID,
-		contact.Version,
-	}
+	args := append(contactArgs(contact), contact.ID, contact.Version)
 
-	err := repository.DB.QueryRowContext(ctx, query, args...).Scan(&contact.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&contact.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -108,9 +197,563 @@ func (repository *SQLContactRepository) Update(contact *domain.Contact, ctx cont
 			return err
 		}
 	}
+
+	err = recordContactHistory(ctx, tx, domain.HistoryUpdated, before, contact)
+	if err != nil {
+		return err
+	}
+
+	err = recordEvent(ctx, tx, contact.OwnerID, domain.ContactUpdated{Contact: *contact})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListGroups implements domain.ContactRepository
+func (repository *SQLContactRepository) ListGroups(ctx context.Context, contactID int64, ownerID int64) ([]*domain.Group, error) {
+	if contactID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM contacts WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL)`
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, contactID, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecordNotFound
+	}
+
+	query = `
+		SELECT g.id, g.owner_id, g.parent_id, g.group_name, g.created_at, g.version
+		FROM groups g
+		INNER JOIN group_members gm ON gm.group_id = g.id
+		WHERE gm.contact_id = $1
+		ORDER BY g.id`
+
+	rows, err := repository.DB.QueryContext(ctx, query, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*domain.Group{}
+
+	for rows.Next() {
+		var group domain.Group
+
+		err := rows.Scan(
+			&group.ID,
+			&group.OwnerID,
+			&group.ParentID,
+			&group.GroupName,
+			&group.CreatedAt,
+			&group.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, &group)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// List implements domain.ContactRepository
+func (repository *SQLContactRepository) List(ctx context.Context, ownerID int64, fullName string, phone string, filters domain.Filters) ([]*domain.Contact, domain.Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		       company, title, birthday, notes, custom_fields, created_at, deleted_at, version
+		FROM contacts
+		WHERE owner_id = $6 AND deleted_at IS NULL
+		AND (to_tsvector('simple', full_name) @@ plainto_tsquery('simple', $1)
+		     OR full_name ILIKE '%%' || $2 || '%%' OR $1 = '')
+		AND (phone LIKE $3 || '%%' OR phone_e164 LIKE $3 || '%%' OR $3 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $4 OFFSET $5`, filters.SortColumn(), filters.SortDirection())
+
+	args := []any{
+		fullName,
+		escapeLike(fullName),
+		escapeLike(phone),
+		filters.Limit(),
+		filters.Offset(),
+		ownerID,
+	}
+
+	rows, err := repository.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	contacts := []*domain.Contact{}
+
+	for rows.Next() {
+		var contact domain.Contact
+
+		err := rows.Scan(append([]any{&totalRecords}, contactFields(&contact)...)...)
+		if err != nil {
+			return nil, domain.Metadata{}, err
+		}
+
+		contacts = append(contacts, &contact)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return contacts, metadata, nil
+}
+
+// Export implements domain.ContactRepository. A groupID of 0 exports every
+// contact.
+func (repository *SQLContactRepository) Export(ctx context.Context, ownerID int64, groupID int64) (domain.Cursor[domain.Contact], error) {
+	if groupID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	if groupID > 0 {
+		query := `
+			SELECT EXISTS(SELECT 1 FROM groups WHERE id = $1 AND owner_id = $2)`
+
+		var exists bool
+		err := repository.DB.QueryRowContext(ctx, query, groupID, ownerID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrRecordNotFound
+		}
+	}
+
+	query := `
+		SELECT c.id, c.owner_id, c.full_name, c.phone, c.phone_e164, c.phones, c.emails, c.addresses,
+		       c.company, c.title, c.birthday, c.notes, c.custom_fields, c.created_at, c.deleted_at, c.version
+		FROM contacts c
+		WHERE c.owner_id = $1 AND c.deleted_at IS NULL
+		AND ($2 = 0 OR EXISTS (
+			SELECT 1 FROM group_members gm
+			WHERE gm.contact_id = c.id AND gm.group_id = $2))
+		ORDER BY c.id`
+
+	rows, err := repository.DB.QueryContext(ctx, query, ownerID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRowsCursor(rows, func(rows *sql.Rows, contact *domain.Contact) error {
+		return rows.Scan(contactFields(contact)...)
+	}), nil
+}
+
+// ListDeleted implements domain.ContactRepository
+func (repository *SQLContactRepository) ListDeleted(ctx context.Context, ownerID int64, filters domain.Filters) ([]*domain.Contact, domain.Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		       company, title, birthday, notes, custom_fields, created_at, deleted_at, version
+		FROM contacts
+		WHERE owner_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
+
+	rows, err := repository.DB.QueryContext(ctx, query, ownerID, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	contacts := []*domain.Contact{}
+
+	for rows.Next() {
+		var contact domain.Contact
+
+		err := rows.Scan(append([]any{&totalRecords}, contactFields(&contact)...)...)
+		if err != nil {
+			return nil, domain.Metadata{}, err
+		}
+
+		contacts = append(contacts, &contact)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return contacts, metadata, nil
+}
+
+// Restore implements domain.ContactRepository
+func (repository *SQLContactRepository) Restore(ctx context.Context, id int64, ownerID int64) (*domain.Contact, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getContactForUpdate(ctx, tx, id, ownerID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE contacts
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1
+		RETURNING id, owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		          company, title, birthday, notes, custom_fields, created_at, deleted_at, version`
+
+	var contact domain.Contact
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(contactFields(&contact)...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordContactHistory(ctx, tx, domain.HistoryRestored, before, &contact)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordEvent(ctx, tx, ownerID, domain.ContactRestored{Contact: contact})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
+// Purge implements domain.ContactRepository. Memberships of the purged
+// contacts are removed by the group_members foreign key. Their history is
+// kept: the deletion was recorded when they moved to the trash.
+func (repository *SQLContactRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := `
+		DELETE FROM contacts
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+
+	result, err := repository.DB.ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ListMissingPhoneE164 implements domain.ContactRepository. It returns up to
+// limit contacts, in the trash or not, with an ID above afterID and a number
+// without E.164 form, in order of ID.
+func (repository *SQLContactRepository) ListMissingPhoneE164(ctx context.Context, afterID int64, limit int) ([]*domain.Contact, error) {
+	query := `
+		SELECT id, owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		       company, title, birthday, notes, custom_fields, created_at, deleted_at, version
+		FROM contacts
+		WHERE id > $1 AND (
+			(phone <> '' AND phone_e164 = '') OR
+			EXISTS (SELECT 1 FROM jsonb_array_elements(phones) AS p WHERE COALESCE(p->>'e164', '') = '')
+		)
+		ORDER BY id
+		LIMIT $2`
+
+	rows, err := repository.DB.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []*domain.Contact{}
+
+	for rows.Next() {
+		var contact domain.Contact
+
+		err := rows.Scan(contactFields(&contact)...)
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, &contact)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// SetPhoneE164 implements domain.ContactRepository. Only the E.164 forms of
+// the numbers are written, and only while the contact is at the same
+// version; the version is kept, as nothing a user sees changes, and so is
+// the history.
+func (repository *SQLContactRepository) SetPhoneE164(ctx context.Context, contact *domain.Contact) error {
+	query := `
+		UPDATE contacts
+		SET phone_e164 = $1, phones = $2
+		WHERE id = $3 AND version = $4`
+
+	result, err := repository.DB.ExecContext(ctx, query, contact.PhoneE164, jsonColumn{&contact.Phones}, contact.ID, contact.Version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
 	return nil
 }
 
+// Merge implements domain.ContactRepository. Both contacts are locked in
+// order of ID, so that concurrent merges of the same pair cannot deadlock.
+// Moving a membership the contact already has only removes the duplicate
+// from the group.
+func (repository *SQLContactRepository) Merge(ctx context.Context, id int64, duplicateID int64, ownerID int64) (*domain.Contact, error) {
+	if id < 1 || duplicateID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	lockOrder := []int64{id, duplicateID}
+	if duplicateID < id {
+		lockOrder = []int64{duplicateID, id}
+	}
+
+	locked := make(map[int64]*domain.Contact, 2)
+	for _, contactID := range lockOrder {
+		contact, err := getContactForUpdate(ctx, tx, contactID, ownerID, false)
+		if err != nil {
+			return nil, err
+		}
+		locked[contactID] = contact
+	}
+	before, duplicate := locked[id], locked[duplicateID]
+
+	contact := cloneContact(*before)
+	domain.MergeContacts(&contact, duplicate)
+
+	query := `
+		UPDATE contacts
+		SET full_name = $2, phone = $3, phone_e164 = $4, phones = $5, emails = $6, addresses = $7,
+		    company = $8, title = $9, birthday = $10, notes = $11, custom_fields = $12,
+		    version = version + 1
+		WHERE owner_id = $1 AND id = $13
+		RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, append(contactArgs(&contact), id)...).Scan(&contact.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordContactHistory(ctx, tx, domain.HistoryMerged, before, &contact)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordEvent(ctx, tx, ownerID, domain.ContactUpdated{Contact: contact})
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		DELETE FROM group_members
+		WHERE contact_id = $1
+		RETURNING group_id`
+
+	rows, err := tx.QueryContext(ctx, query, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs := []int64{}
+	for rows.Next() {
+		var groupID int64
+		if err := rows.Scan(&groupID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, groupID := range groupIDs {
+		err = recordEvent(ctx, tx, ownerID, domain.GroupMemberRemoved{GroupID: groupID, ContactID: duplicateID})
+		if err != nil {
+			return nil, err
+		}
+
+		query = `
+			INSERT INTO group_members (group_id, contact_id)
+			VALUES ($1, $2)
+			ON CONFLICT (group_id, contact_id) DO NOTHING`
+
+		result, err := tx.ExecContext(ctx, query, groupID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		added, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if added == 0 {
+			continue
+		}
+
+		err = recordEvent(ctx, tx, ownerID, domain.GroupMemberAdded{GroupID: groupID, ContactID: id})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query = `
+		UPDATE contacts
+		SET deleted_at = NOW(), version = version + 1
+		WHERE id = $1
+		RETURNING deleted_at, version`
+
+	deleted := *duplicate
+	err = tx.QueryRowContext(ctx, query, duplicateID).Scan(&deleted.DeletedAt, &deleted.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordContactHistory(ctx, tx, domain.HistoryDeleted, duplicate, &deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordEvent(ctx, tx, ownerID, domain.ContactDeleted{ContactID: duplicateID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
+// getContactForUpdate reads and locks a contact within tx, looking among the
+// contacts in the trash when deleted is set and among the live ones otherwise.
+func getContactForUpdate(ctx context.Context, tx *sql.Tx, id int64, ownerID int64, deleted bool) (*domain.Contact, error) {
+	query := `
+		SELECT id, owner_id, full_name, phone, phone_e164, phones, emails, addresses,
+		       company, title, birthday, notes, custom_fields, created_at, deleted_at, version
+		FROM contacts
+		WHERE id = $1 AND owner_id = $2 AND (deleted_at IS NOT NULL) = $3
+		FOR UPDATE`
+
+	var contact domain.Contact
+
+	err := tx.QueryRowContext(ctx, query, id, ownerID, deleted).Scan(contactFields(&contact)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &contact, nil
+}
+
+// recordContactHistory records the change of a contact from before to after
+// within tx.
+func recordContactHistory(ctx context.Context, tx *sql.Tx, action domain.HistoryAction, before *domain.Contact, after *domain.Contact) error {
+	entry, err := domain.NewHistoryEntry(ctx, domain.EntityContact, after.ID, after.OwnerID, after.Version, action, before, after)
+	if err != nil {
+		return err
+	}
+	return recordHistory(ctx, tx, entry)
+}
+
+// contactArgs returns the owner and the editable fields of contact, in the
+// order the INSERT and UPDATE statements of this file expect them as $1 to
+// $12.
+func contactArgs(contact *domain.Contact) []any {
+	return []any{
+		contact.OwnerID,
+		contact.FullName,
+		contact.Phone,
+		contact.PhoneE164,
+		jsonColumn{&contact.Phones},
+		jsonColumn{&contact.Emails},
+		jsonColumn{&contact.Addresses},
+		contact.Company,
+		contact.Title,
+		contact.Birthday,
+		contact.Notes,
+		jsonColumn{&contact.CustomFields},
+	}
+}
+
+// contactFields returns the scan destinations for the contact columns, in the
+// order every query of this package selects them.
+func contactFields(contact *domain.Contact) []any {
+	return []any{
+		&contact.ID,
+		&contact.OwnerID,
+		&contact.FullName,
+		&contact.Phone,
+		&contact.PhoneE164,
+		jsonColumn{&contact.Phones},
+		jsonColumn{&contact.Emails},
+		jsonColumn{&contact.Addresses},
+		&contact.Company,
+		&contact.Title,
+		&contact.Birthday,
+		&contact.Notes,
+		jsonColumn{&contact.CustomFields},
+		&contact.CreatedAt,
+		&contact.DeletedAt,
+		&contact.Version,
+	}
+}
+
+// escapeLike escapes the LIKE wildcard characters so that user input is
+// matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
 func NewContactRepository(conn *sql.DB) domain.ContactRepository {
 	return &SQLContactRepository{conn}
 }
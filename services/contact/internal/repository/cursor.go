@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// rowsCursor implements domain.Cursor on top of sql.Rows.
+type rowsCursor[T any] struct {
+	rows  *sql.Rows
+	scan  func(rows *sql.Rows, value *T) error
+	value *T
+	err   error
+}
+
+func newRowsCursor[T any](rows *sql.Rows, scan func(rows *sql.Rows, value *T) error) *rowsCursor[T] {
+	return &rowsCursor[T]{rows: rows, scan: scan}
+}
+
+func (cursor *rowsCursor[T]) Next() bool {
+	if cursor.err != nil || !cursor.rows.Next() {
+		return false
+	}
+
+	var value T
+	if err := cursor.scan(cursor.rows, &value); err != nil {
+		cursor.err = err
+		return false
+	}
+
+	cursor.value = &value
+	return true
+}
+
+func (cursor *rowsCursor[T]) Value() *T {
+	return cursor.value
+}
+
+func (cursor *rowsCursor[T]) Err() error {
+	if cursor.err != nil {
+		return cursor.err
+	}
+	return cursor.rows.Err()
+}
+
+func (cursor *rowsCursor[T]) Close() error {
+	return cursor.rows.Close()
+}
+
+// sliceCursor implements domain.Cursor over a snapshot taken by the memory
+// repositories.
+type sliceCursor[T any] struct {
+	values []T
+	index  int
+}
+
+func newSliceCursor[T any](values []T) *sliceCursor[T] {
+	return &sliceCursor[T]{values: values, index: -1}
+}
+
+func (cursor *sliceCursor[T]) Next() bool {
+	if cursor.index+1 >= len(cursor.values) {
+		return false
+	}
+	cursor.index++
+	return true
+}
+
+func (cursor *sliceCursor[T]) Value() *T {
+	if cursor.index < 0 || cursor.index >= len(cursor.values) {
+		return nil
+	}
+	value := cursor.values[cursor.index]
+	return &value
+}
+
+func (cursor *sliceCursor[T]) Err() error {
+	return nil
+}
+
+func (cursor *sliceCursor[T]) Close() error {
+	return nil
+}
@@ -1,8 +1,23 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
 
 var (
-	ErrRecordNotFound = errors.New("record not found")
-	ErrEditConflict   = errors.New("edit conflict")
+	ErrRecordNotFound  = domain.ErrRecordNotFound
+	ErrEditConflict    = domain.ErrEditConflict
+	ErrDuplicateMember = errors.New("duplicate member")
+	ErrDuplicateEmail  = errors.New("duplicate email")
+	// ErrDuplicateGroupName is returned when an owner already has a group
+	// with the same name, ignoring case.
+	ErrDuplicateGroupName = errors.New("duplicate group name")
+	// ErrGroupNotEmpty is returned when deleting a group that still has
+	// members or subgroups under domain.GroupDeleteRestrict.
+	ErrGroupNotEmpty = errors.New("group not empty")
+	// ErrInvalidParent is returned when the parent of a group does not exist
+	// or, when moving a group, lies within the group's own subtree.
+	ErrInvalidParent = errors.New("invalid parent group")
 )
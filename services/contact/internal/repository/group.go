@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"advanced.microservices/services/contact/internal/domain"
+	"github.com/lib/pq"
 )
 
 type SQLGroupRepository struct {
@@ -13,36 +14,63 @@ type SQLGroupRepository struct {
 }
 
 // Create implements domain.GroupRepository
-func (repository *SQLGroupRepository) Create(group *domain.Group, ctx context.Context) error {
+func (repository *SQLGroupRepository) Create(ctx context.Context, group *domain.Group) error {
 	query := `
-		INSERT INTO groups (group_name)
-		VALUES ($1)
-		RETURNING id, created_at`
-	args := []any{group.GroupName}
+		INSERT INTO groups (owner_id, parent_id, group_name)
+		SELECT $1, $2, $3
+		WHERE $2::bigint IS NULL OR EXISTS(SELECT 1 FROM groups WHERE id = $2 AND owner_id = $1)
+		RETURNING id, created_at, version`
+	args := []any{group.OwnerID, group.ParentID, group.GroupName}
 
-	err := repository.DB.QueryRowContext(ctx, query, args...).Scan(&group.ID, &group.CreatedAt)
+	tx, err := repository.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&group.ID, &group.CreatedAt, &group.Version)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrInvalidParent
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return ErrDuplicateGroupName
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			// The parent was deleted concurrently.
+			return ErrInvalidParent
+		default:
+			return err
+		}
+	}
+
+	err = recordGroupHistory(ctx, tx, domain.HistoryCreated, nil, group)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // GetByID implements domain.GroupRepository
-func (repository *SQLGroupRepository) GetByID(id int64, ctx context.Context) (*domain.Group, error) {
+func (repository *SQLGroupRepository) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Group, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, full_name, phone, version
+		SELECT id, owner_id, parent_id, group_name, created_at, version
 		FROM groups
-		WHERE id = $1`
+		WHERE id = $1 AND owner_id = $2`
 
 	var group domain.Group
 
-	err := repository.DB.QueryRowContext(ctx, query, id).Scan(
+	err := repository.DB.QueryRowContext(ctx, query, id, ownerID).Scan(
 		&group.ID,
+		&group.OwnerID,
+		&group.ParentID,
 		&group.GroupName,
+		&group.CreatedAt,
 		&group.Version,
 	)
 
@@ -59,30 +87,610 @@ func (repository *SQLGroupRepository) GetByID(id int64, ctx context.Context) (*d
 }
 
 // Update implements domain.GroupRepository
-func (repository *SQLGroupRepository) Update(group *domain.Group, ctx context.Context) error {
+func (repository *SQLGroupRepository) Update(ctx context.Context, group *domain.Group) error {
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getGroupForUpdate(ctx, tx, group.ID, group.OwnerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	if before.Version != group.Version {
+		return ErrEditConflict
+	}
+
 	query := `
 		UPDATE groups
 		SET group_name = $1, version = version + 1
-		WHERE id = $2 AND version = $3
+		WHERE id = $2 AND owner_id = $3 AND version = $4
 		RETURNING version`
 
 	args := []any{
 		group.GroupName,
 		group.ID,
+		group.OwnerID,
 		group.Version,
 	}
 
-	err := repository.DB.QueryRowContext(ctx, query, args...).Scan(&group.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&group.Version)
 	if err != nil {
+		var pqErr *pq.Error
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return ErrDuplicateGroupName
+		default:
+			return err
+		}
+	}
+
+	err = recordGroupHistory(ctx, tx, domain.HistoryUpdated, before, group)
+	if err != nil {
+		return err
+	}
+
+	if group.GroupName != before.GroupName {
+		event := domain.GroupRenamed{GroupID: group.ID, OldName: before.GroupName, NewName: group.GroupName}
+		if err = recordEvent(ctx, tx, group.OwnerID, event); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete implements domain.GroupRepository. Subgroups and memberships are
+// removed by the foreign keys. Contacts in the trash do not count as members
+// under domain.GroupDeleteRestrict.
+func (repository *SQLGroupRepository) Delete(ctx context.Context, id int64, ownerID int64, policy domain.GroupDeletePolicy) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the group keeps members and subgroups from being added between
+	// the check and the delete.
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM group_members gm
+			INNER JOIN contacts c ON c.id = gm.contact_id
+			WHERE gm.group_id = g.id AND c.deleted_at IS NULL)
+		OR EXISTS(SELECT 1 FROM groups sg WHERE sg.parent_id = g.id)
+		FROM groups g
+		WHERE g.id = $1 AND g.owner_id = $2
+		FOR UPDATE OF g`
+
+	var hasMembers bool
+	err = tx.QueryRowContext(ctx, query, id, ownerID).Scan(&hasMembers)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if hasMembers && policy == domain.GroupDeleteRestrict {
+		return ErrGroupNotEmpty
+	}
+
+	// Every group of the subtree is recorded as deleted, since the foreign
+	// key removes the subgroups without further notice.
+	query = `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM groups WHERE id = $1
+			UNION ALL
+			SELECT g.id FROM groups g INNER JOIN subtree s ON g.parent_id = s.id
+		)
+		SELECT id, owner_id, parent_id, group_name, created_at, version
+		FROM groups
+		WHERE id IN (SELECT id FROM subtree)
+		ORDER BY id
+		FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	groups, err := scanGroups(rows)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, len(groups))
+	for i, group := range groups {
+		ids[i] = group.ID
+	}
+
+	// So are the memberships the foreign key removes, trashed contacts
+	// included.
+	query = `
+		SELECT group_id, contact_id
+		FROM group_members
+		WHERE group_id = ANY($1)
+		ORDER BY group_id, contact_id`
+
+	rows, err = tx.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	members := []domain.GroupMemberRemoved{}
+	for rows.Next() {
+		var member domain.GroupMemberRemoved
+		if err := rows.Scan(&member.GroupID, &member.ContactID); err != nil {
+			rows.Close()
+			return err
+		}
+		members = append(members, member)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		err = recordEvent(ctx, tx, ownerID, member)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, group := range groups {
+		err = recordGroupHistory(ctx, tx, domain.HistoryDeleted, group, nil)
+		if err != nil {
+			return err
+		}
+		err = recordEvent(ctx, tx, ownerID, domain.GroupDeleted{GroupID: group.ID})
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `
+		DELETE FROM groups
+		WHERE id = $1`
+
+	_, err = tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Move implements domain.GroupRepository
+func (repository *SQLGroupRepository) Move(ctx context.Context, id int64, parentID *int64, ownerID int64) (*domain.Group, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Two concurrent moves could each pass the cycle check below and still
+	// close a cycle together, so the moves of an owner are serialised on
+	// their user row.
+	query := `
+		SELECT EXISTS(SELECT 1 FROM groups WHERE id = $1 AND owner_id = $2)
+		FROM users
+		WHERE id = $2
+		FOR NO KEY UPDATE`
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, query, id, ownerID).Scan(&exists)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecordNotFound
+	}
+
+	before, err := getGroupForUpdate(ctx, tx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != nil {
+		query = `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM groups WHERE id = $1
+				UNION ALL
+				SELECT g.id FROM groups g INNER JOIN subtree s ON g.parent_id = s.id
+			)
+			SELECT EXISTS(SELECT 1 FROM groups WHERE id = $2 AND owner_id = $3),
+			       EXISTS(SELECT 1 FROM subtree WHERE id = $2)`
+
+		var parentExists, inSubtree bool
+		err = tx.QueryRowContext(ctx, query, id, *parentID, ownerID).Scan(&parentExists, &inSubtree)
+		if err != nil {
+			return nil, err
+		}
+		if !parentExists || inSubtree {
+			return nil, ErrInvalidParent
+		}
+	}
+
+	query = `
+		UPDATE groups
+		SET parent_id = $1, version = version + 1
+		WHERE id = $2
+		RETURNING id, owner_id, parent_id, group_name, created_at, version`
+
+	var group domain.Group
+
+	err = tx.QueryRowContext(ctx, query, parentID, id).Scan(
+		&group.ID,
+		&group.OwnerID,
+		&group.ParentID,
+		&group.GroupName,
+		&group.CreatedAt,
+		&group.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordGroupHistory(ctx, tx, domain.HistoryUpdated, before, &group)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// Ancestors implements domain.GroupRepository
+func (repository *SQLGroupRepository) Ancestors(ctx context.Context, id int64, ownerID int64) ([]*domain.Group, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM groups WHERE id = $1 AND owner_id = $2)`
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, id, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecordNotFound
+	}
+
+	query = `
+		WITH RECURSIVE ancestors AS (
+			SELECT p.id, p.owner_id, p.parent_id, p.group_name, p.created_at, p.version, 1 AS depth
+			FROM groups g
+			INNER JOIN groups p ON p.id = g.parent_id
+			WHERE g.id = $1
+			UNION ALL
+			SELECT p.id, p.owner_id, p.parent_id, p.group_name, p.created_at, p.version, a.depth + 1
+			FROM groups p
+			INNER JOIN ancestors a ON p.id = a.parent_id
+		)
+		SELECT id, owner_id, parent_id, group_name, created_at, version
+		FROM ancestors
+		ORDER BY depth`
+
+	rows, err := repository.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGroups(rows)
+}
+
+// Descendants implements domain.GroupRepository
+func (repository *SQLGroupRepository) Descendants(ctx context.Context, id int64, ownerID int64) ([]*domain.Group, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM groups WHERE id = $1 AND owner_id = $2)`
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, id, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecordNotFound
+	}
+
+	query = `
+		WITH RECURSIVE descendants AS (
+			SELECT g.id, g.owner_id, g.parent_id, g.group_name, g.created_at, g.version, 1 AS depth
+			FROM groups g
+			WHERE g.parent_id = $1
+			UNION ALL
+			SELECT g.id, g.owner_id, g.parent_id, g.group_name, g.created_at, g.version, d.depth + 1
+			FROM groups g
+			INNER JOIN descendants d ON g.parent_id = d.id
+		)
+		SELECT id, owner_id, parent_id, group_name, created_at, version
+		FROM descendants
+		ORDER BY depth, id`
+
+	rows, err := repository.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGroups(rows)
+}
+
+// AddMember implements domain.GroupRepository
+func (repository *SQLGroupRepository) AddMember(ctx context.Context, member *domain.GroupMember, ownerID int64) error {
+	if member.GroupID < 1 || member.ContactID < 1 {
+		return ErrRecordNotFound
+	}
+
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM groups WHERE id = $1 AND owner_id = $3),
+		       EXISTS(SELECT 1 FROM contacts WHERE id = $2 AND owner_id = $3 AND deleted_at IS NULL)`
+
+	var groupExists, contactExists bool
+	err = tx.QueryRowContext(ctx, query, member.GroupID, member.ContactID, ownerID).Scan(&groupExists, &contactExists)
+	if err != nil {
+		return err
+	}
+	if !groupExists || !contactExists {
+		return ErrRecordNotFound
+	}
+
+	query = `
+		INSERT INTO group_members (group_id, contact_id)
+		VALUES ($1, $2)
+		ON CONFLICT (group_id, contact_id) DO NOTHING
+		RETURNING created_at`
+
+	err = tx.QueryRowContext(ctx, query, member.GroupID, member.ContactID).Scan(&member.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDuplicateMember
 		default:
 			return err
 		}
 	}
 
-	return nil
+	err = recordEvent(ctx, tx, ownerID, domain.GroupMemberAdded{GroupID: member.GroupID, ContactID: member.ContactID})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveMember implements domain.GroupRepository
+func (repository *SQLGroupRepository) RemoveMember(ctx context.Context, groupID int64, contactID int64, ownerID int64) error {
+	if groupID < 1 || contactID < 1 {
+		return ErrRecordNotFound
+	}
+
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM group_members gm
+		USING groups g
+		WHERE gm.group_id = g.id
+		AND gm.group_id = $1 AND gm.contact_id = $2 AND g.owner_id = $3`
+
+	result, err := tx.ExecContext(ctx, query, groupID, contactID, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	err = recordEvent(ctx, tx, ownerID, domain.GroupMemberRemoved{GroupID: groupID, ContactID: contactID})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListMembers implements domain.GroupRepository
+func (repository *SQLGroupRepository) ListMembers(ctx context.Context, groupID int64, ownerID int64, includeSubgroups bool) ([]*domain.Contact, error) {
+	if groupID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM groups WHERE id = $1 AND owner_id = $2)`
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, groupID, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecordNotFound
+	}
+
+	query = `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM groups WHERE id = $1
+			UNION ALL
+			SELECT g.id FROM groups g INNER JOIN subtree s ON g.parent_id = s.id
+			WHERE $2
+		)
+		SELECT c.id, c.owner_id, c.full_name, c.phone, c.phone_e164, c.phones, c.emails, c.addresses,
+		       c.company, c.title, c.birthday, c.notes, c.custom_fields, c.created_at, c.deleted_at, c.version
+		FROM contacts c
+		WHERE c.deleted_at IS NULL
+		AND EXISTS (
+			SELECT 1 FROM group_members gm
+			WHERE gm.contact_id = c.id AND gm.group_id IN (SELECT id FROM subtree))
+		ORDER BY c.id`
+
+	rows, err := repository.DB.QueryContext(ctx, query, groupID, includeSubgroups)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []*domain.Contact{}
+
+	for rows.Next() {
+		var contact domain.Contact
+
+		err := rows.Scan(contactFields(&contact)...)
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, &contact)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// Export implements domain.GroupRepository
+func (repository *SQLGroupRepository) Export(ctx context.Context, ownerID int64) (domain.Cursor[domain.Group], error) {
+	query := `
+		SELECT id, owner_id, parent_id, group_name, created_at, version
+		FROM groups
+		WHERE owner_id = $1
+		ORDER BY id`
+
+	rows, err := repository.DB.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRowsCursor(rows, func(rows *sql.Rows, group *domain.Group) error {
+		return rows.Scan(
+			&group.ID,
+			&group.OwnerID,
+			&group.ParentID,
+			&group.GroupName,
+			&group.CreatedAt,
+			&group.Version,
+		)
+	}), nil
+}
+
+// getGroupForUpdate reads and locks a group within tx.
+func getGroupForUpdate(ctx context.Context, tx *sql.Tx, id int64, ownerID int64) (*domain.Group, error) {
+	query := `
+		SELECT id, owner_id, parent_id, group_name, created_at, version
+		FROM groups
+		WHERE id = $1 AND owner_id = $2
+		FOR UPDATE`
+
+	var group domain.Group
+
+	err := tx.QueryRowContext(ctx, query, id, ownerID).Scan(
+		&group.ID,
+		&group.OwnerID,
+		&group.ParentID,
+		&group.GroupName,
+		&group.CreatedAt,
+		&group.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &group, nil
+}
+
+// recordGroupHistory records the change of a group from before to after
+// within tx. A nil after records its deletion, as the version following
+// before.
+func recordGroupHistory(ctx context.Context, tx *sql.Tx, action domain.HistoryAction, before *domain.Group, after *domain.Group) error {
+	var entry *domain.HistoryEntry
+	var err error
+	if after != nil {
+		entry, err = domain.NewHistoryEntry(ctx, domain.EntityGroup, after.ID, after.OwnerID, after.Version, action, before, after)
+	} else {
+		entry, err = domain.NewHistoryEntry(ctx, domain.EntityGroup, before.ID, before.OwnerID, before.Version+1, action, before, nil)
+	}
+	if err != nil {
+		return err
+	}
+	return recordHistory(ctx, tx, entry)
+}
+
+// scanGroups reads every group of rows and closes it.
+func scanGroups(rows *sql.Rows) ([]*domain.Group, error) {
+	defer rows.Close()
+
+	groups := []*domain.Group{}
+
+	for rows.Next() {
+		var group domain.Group
+
+		err := rows.Scan(
+			&group.ID,
+			&group.OwnerID,
+			&group.ParentID,
+			&group.GroupName,
+			&group.CreatedAt,
+			&group.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, &group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
 }
 
 func NewGroupRepository(conn *sql.DB) domain.GroupRepository {
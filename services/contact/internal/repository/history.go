@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"advanced.microservices/services/contact/internal/domain"
+	"github.com/lib/pq"
+)
+
+type SQLHistoryRepository struct {
+	DB *sql.DB
+}
+
+// List implements domain.HistoryRepository
+func (repository *SQLHistoryRepository) List(ctx context.Context, entityType domain.EntityType, entityID int64, ownerID int64, filters domain.Filters) ([]*domain.HistoryEntry, domain.Metadata, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM history WHERE entity_type = $1 AND entity_id = $2 AND owner_id = $3)`
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, entityType, entityID, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+	if !exists {
+		return nil, domain.Metadata{}, ErrRecordNotFound
+	}
+
+	query = fmt.Sprintf(`
+		SELECT count(*) OVER(), id, entity_type, entity_id, owner_id, version, action,
+		       actor_id, before, after, changed_fields, created_at
+		FROM history
+		WHERE entity_type = $1 AND entity_id = $2 AND owner_id = $3
+		ORDER BY %s %s
+		LIMIT $4 OFFSET $5`, filters.SortColumn(), filters.SortDirection())
+
+	rows, err := repository.DB.QueryContext(ctx, query, entityType, entityID, ownerID, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*domain.HistoryEntry{}
+
+	for rows.Next() {
+		var entry domain.HistoryEntry
+
+		err := rows.Scan(append([]any{&totalRecords}, historyFields(&entry)...)...)
+		if err != nil {
+			return nil, domain.Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+// GetVersion implements domain.HistoryRepository
+func (repository *SQLHistoryRepository) GetVersion(ctx context.Context, entityType domain.EntityType, entityID int64, ownerID int64, version int32) (*domain.HistoryEntry, error) {
+	query := `
+		SELECT id, entity_type, entity_id, owner_id, version, action,
+		       actor_id, before, after, changed_fields, created_at
+		FROM history
+		WHERE entity_type = $1 AND entity_id = $2 AND owner_id = $3 AND version = $4`
+
+	var entry domain.HistoryEntry
+
+	err := repository.DB.QueryRowContext(ctx, query, entityType, entityID, ownerID, version).Scan(historyFields(&entry)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+// recordHistory appends entry to the history within tx, the transaction of
+// the change it records.
+func recordHistory(ctx context.Context, tx *sql.Tx, entry *domain.HistoryEntry) error {
+	query := `
+		INSERT INTO history (entity_type, entity_id, owner_id, version, action, actor_id, before, after, changed_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	args := []any{
+		entry.EntityType,
+		entry.EntityID,
+		entry.OwnerID,
+		entry.Version,
+		entry.Action,
+		entry.ActorID,
+		jsonbArg(entry.Before),
+		jsonbArg(entry.After),
+		pq.Array(entry.ChangedFields),
+	}
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// historyFields returns the scan destinations for the history columns, in
+// the order the queries of this file select them.
+func historyFields(entry *domain.HistoryEntry) []any {
+	return []any{
+		&entry.ID,
+		&entry.EntityType,
+		&entry.EntityID,
+		&entry.OwnerID,
+		&entry.Version,
+		&entry.Action,
+		&entry.ActorID,
+		(*[]byte)(&entry.Before),
+		(*[]byte)(&entry.After),
+		pq.Array(&entry.ChangedFields),
+		&entry.CreatedAt,
+	}
+}
+
+// jsonbArg passes raw JSON as text, since byte slices would be sent as bytea,
+// and an empty value as NULL.
+func jsonbArg(raw []byte) any {
+	if raw == nil {
+		return nil
+	}
+	return string(raw)
+}
+
+func NewHistoryRepository(conn *sql.DB) domain.HistoryRepository {
+	return &SQLHistoryRepository{conn}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// jsonColumn reads and writes the value dest points to as a jsonb column.
+// Nil slices and maps are stored as empty ones so that the column never holds
+// a JSON null.
+type jsonColumn struct {
+	dest any
+}
+
+func (column jsonColumn) Value() (driver.Value, error) {
+	value := reflect.ValueOf(column.dest).Elem()
+	switch {
+	case value.Kind() == reflect.Slice && value.IsNil():
+		return "[]", nil
+	case value.Kind() == reflect.Map && value.IsNil():
+		return "{}", nil
+	}
+
+	// Strings, unlike byte slices, are sent as text rather than bytea.
+	js, err := json.Marshal(column.dest)
+	if err != nil {
+		return nil, err
+	}
+	return string(js), nil
+}
+
+func (column jsonColumn) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, column.dest)
+	case string:
+		return json.Unmarshal([]byte(src), column.dest)
+	default:
+		return fmt.Errorf("cannot scan %T into a jsonb column", src)
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// MemoryStore keeps users, contacts, groups and memberships in process
+// memory. It is shared by the memory repositories so that membership lookups
+// and cascading deletes see a consistent view, exactly like the SQL tables
+// would.
+type MemoryStore struct {
+	mu             sync.RWMutex
+	contacts       map[int64]domain.Contact
+	groups         map[int64]domain.Group
+	members        map[int64]map[int64]time.Time
+	users          map[int64]domain.User
+	tokens         map[string]domain.Token
+	permissions    map[int64]map[string]bool
+	history        []domain.HistoryEntry
+	outbox         []outboxEntry
+	webhooks       map[int64]domain.Webhook
+	deliveries     map[int64]domain.WebhookDelivery
+	lastContactID  int64
+	lastGroupID    int64
+	lastUserID     int64
+	lastHistoryID  int64
+	lastEventID    int64
+	lastWebhookID  int64
+	lastDeliveryID int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		contacts:    make(map[int64]domain.Contact),
+		groups:      make(map[int64]domain.Group),
+		members:     make(map[int64]map[int64]time.Time),
+		users:       make(map[int64]domain.User),
+		tokens:      make(map[string]domain.Token),
+		permissions: make(map[int64]map[string]bool),
+		webhooks:    make(map[int64]domain.Webhook),
+		deliveries:  make(map[int64]domain.WebhookDelivery),
+	}
+}
+
+// now mirrors the timestamp(0) precision of the SQL schema.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// recordHistory appends the change of a record from before to after to the
+// history. The caller must hold the write lock and call it before changing the
+// record, so that a failure leaves the store untouched.
+func (store *MemoryStore) recordHistory(ctx context.Context, entityType domain.EntityType, entityID int64, ownerID int64, version int32, action domain.HistoryAction, before any, after any) error {
+	entry, err := domain.NewHistoryEntry(ctx, entityType, entityID, ownerID, version, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	store.lastHistoryID++
+	entry.ID = store.lastHistoryID
+	entry.CreatedAt = now()
+
+	store.history = append(store.history, *entry)
+	return nil
+}
+
+// savepoint returns a function dropping the history entries and events
+// recorded since, for changes that fail halfway. The caller must hold the
+// write lock until the change is done.
+func (store *MemoryStore) savepoint() (rollback func()) {
+	history, outbox := len(store.history), len(store.outbox)
+	return func() {
+		store.history = store.history[:history]
+		store.outbox = store.outbox[:outbox]
+	}
+}
+
+// cloneContact copies the slices and maps of contact, so that the stored
+// record cannot be changed through a value handed to or by a repository.
+func cloneContact(contact domain.Contact) domain.Contact {
+	contact.Phones = append([]domain.PhoneNumber(nil), contact.Phones...)
+	contact.Emails = append([]domain.Email(nil), contact.Emails...)
+	contact.Addresses = append([]domain.Address(nil), contact.Addresses...)
+	if contact.Birthday != nil {
+		birthday := *contact.Birthday
+		contact.Birthday = &birthday
+	}
+	if contact.CustomFields != nil {
+		customFields := make(map[string]string, len(contact.CustomFields))
+		for key, value := range contact.CustomFields {
+			customFields[key] = value
+		}
+		contact.CustomFields = customFields
+	}
+	return contact
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
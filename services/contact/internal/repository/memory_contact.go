@@ -0,0 +1,572 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type MemoryContactRepository struct {
+	store *MemoryStore
+}
+
+// Create implements domain.ContactRepository
+func (repository *MemoryContactRepository) Create(ctx context.Context, contact *domain.Contact) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.lastContactID++
+	contact.ID = store.lastContactID
+	contact.CreatedAt = now()
+	contact.Version = 1
+
+	rollback := store.savepoint()
+
+	err := store.recordHistory(ctx, domain.EntityContact, contact.ID, contact.OwnerID, contact.Version, domain.HistoryCreated, nil, contact)
+	if err == nil {
+		err = store.recordEvent(ctx, contact.OwnerID, domain.ContactCreated{Contact: *contact})
+	}
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	store.contacts[contact.ID] = cloneContact(*contact)
+	return nil
+}
+
+// CreateMany implements domain.ContactRepository
+func (repository *MemoryContactRepository) CreateMany(ctx context.Context, contacts []*domain.Contact) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	rollback := store.savepoint()
+
+	createdAt := now()
+	for _, contact := range contacts {
+		store.lastContactID++
+		contact.ID = store.lastContactID
+		contact.CreatedAt = createdAt
+		contact.Version = 1
+
+		err := store.recordHistory(ctx, domain.EntityContact, contact.ID, contact.OwnerID, contact.Version, domain.HistoryCreated, nil, contact)
+		if err == nil {
+			err = store.recordEvent(ctx, contact.OwnerID, domain.ContactCreated{Contact: *contact})
+		}
+		if err != nil {
+			rollback()
+			return err
+		}
+	}
+
+	for _, contact := range contacts {
+		store.contacts[contact.ID] = cloneContact(*contact)
+	}
+	return nil
+}
+
+// Delete implements domain.ContactRepository. Memberships are kept so that
+// Restore can bring them back.
+func (repository *MemoryContactRepository) Delete(ctx context.Context, id int64, ownerID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	contact, ok := store.contacts[id]
+	if !ok || contact.OwnerID != ownerID || contact.DeletedAt != nil {
+		return ErrRecordNotFound
+	}
+
+	before := contact
+	deletedAt := now()
+	contact.DeletedAt = &deletedAt
+	contact.Version++
+
+	rollback := store.savepoint()
+
+	err := store.recordHistory(ctx, domain.EntityContact, id, ownerID, contact.Version, domain.HistoryDeleted, before, contact)
+	if err == nil {
+		err = store.recordEvent(ctx, ownerID, domain.ContactDeleted{ContactID: id})
+	}
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	store.contacts[id] = contact
+	return nil
+}
+
+// GetByID implements domain.ContactRepository
+func (repository *MemoryContactRepository) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	contact, ok := store.contacts[id]
+	if !ok || contact.OwnerID != ownerID || contact.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	contact = cloneContact(contact)
+	return &contact, nil
+}
+
+// Update implements domain.ContactRepository
+func (repository *MemoryContactRepository) Update(ctx context.Context, contact *domain.Contact) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, ok := store.contacts[contact.ID]
+	if !ok || existing.OwnerID != contact.OwnerID || existing.Version != contact.Version || existing.DeletedAt != nil {
+		return ErrEditConflict
+	}
+
+	after := cloneContact(*contact)
+	after.CreatedAt = existing.CreatedAt
+	after.Version++
+
+	rollback := store.savepoint()
+
+	err := store.recordHistory(ctx, domain.EntityContact, after.ID, after.OwnerID, after.Version, domain.HistoryUpdated, existing, after)
+	if err == nil {
+		err = store.recordEvent(ctx, after.OwnerID, domain.ContactUpdated{Contact: after})
+	}
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	contact.CreatedAt = after.CreatedAt
+	contact.Version = after.Version
+	store.contacts[contact.ID] = after
+	return nil
+}
+
+// ListGroups implements domain.ContactRepository
+func (repository *MemoryContactRepository) ListGroups(ctx context.Context, contactID int64, ownerID int64) ([]*domain.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if contact, ok := store.contacts[contactID]; !ok || contact.OwnerID != ownerID || contact.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	groups := []*domain.Group{}
+	for groupID, members := range store.members {
+		if _, ok := members[contactID]; ok {
+			group := store.groups[groupID]
+			groups = append(groups, &group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+	return groups, nil
+}
+
+// List implements domain.ContactRepository
+func (repository *MemoryContactRepository) List(ctx context.Context, ownerID int64, fullName string, phone string, filters domain.Filters) ([]*domain.Contact, domain.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	matched := []*domain.Contact{}
+	for _, contact := range store.contacts {
+		contact := contact
+		if contact.OwnerID == ownerID && contact.DeletedAt == nil && matchesFullName(contact.FullName, fullName) && (strings.HasPrefix(contact.Phone, phone) || strings.HasPrefix(contact.PhoneE164, phone)) {
+			matched = append(matched, &contact)
+		}
+	}
+	store.mu.RUnlock()
+
+	column, desc := filters.SortColumn(), filters.SortDirection() == "DESC"
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		var cmp int
+		switch column {
+		case "id":
+			cmp = compareInt64(a.ID, b.ID)
+		case "full_name":
+			cmp = strings.Compare(a.FullName, b.FullName)
+		case "phone":
+			cmp = strings.Compare(a.Phone, b.Phone)
+		case "created_at":
+			cmp = compareInt64(a.CreatedAt.UnixNano(), b.CreatedAt.UnixNano())
+		}
+		if desc {
+			cmp = -cmp
+		}
+		if cmp == 0 {
+			return a.ID < b.ID
+		}
+		return cmp < 0
+	})
+
+	totalRecords := len(matched)
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	start := filters.Offset()
+	if start > totalRecords {
+		start = totalRecords
+	}
+	end := start + filters.Limit()
+	if end > totalRecords {
+		end = totalRecords
+	}
+
+	return matched[start:end], metadata, nil
+}
+
+// Export implements domain.ContactRepository
+func (repository *MemoryContactRepository) Export(ctx context.Context, ownerID int64, groupID int64) (domain.Cursor[domain.Contact], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if groupID != 0 {
+		if group, ok := store.groups[groupID]; !ok || group.OwnerID != ownerID {
+			return nil, ErrRecordNotFound
+		}
+	}
+
+	contacts := []domain.Contact{}
+	for id, contact := range store.contacts {
+		if contact.OwnerID != ownerID || contact.DeletedAt != nil {
+			continue
+		}
+		if groupID != 0 {
+			if _, ok := store.members[groupID][id]; !ok {
+				continue
+			}
+		}
+		contacts = append(contacts, contact)
+	}
+
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].ID < contacts[j].ID
+	})
+	return newSliceCursor(contacts), nil
+}
+
+// ListDeleted implements domain.ContactRepository
+func (repository *MemoryContactRepository) ListDeleted(ctx context.Context, ownerID int64, filters domain.Filters) ([]*domain.Contact, domain.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	matched := []*domain.Contact{}
+	for _, contact := range store.contacts {
+		contact := contact
+		if contact.OwnerID == ownerID && contact.DeletedAt != nil {
+			matched = append(matched, &contact)
+		}
+	}
+	store.mu.RUnlock()
+
+	column, desc := filters.SortColumn(), filters.SortDirection() == "DESC"
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		var cmp int
+		switch column {
+		case "id":
+			cmp = compareInt64(a.ID, b.ID)
+		case "full_name":
+			cmp = strings.Compare(a.FullName, b.FullName)
+		case "deleted_at":
+			cmp = compareInt64(a.DeletedAt.UnixNano(), b.DeletedAt.UnixNano())
+		}
+		if desc {
+			cmp = -cmp
+		}
+		if cmp == 0 {
+			return a.ID < b.ID
+		}
+		return cmp < 0
+	})
+
+	totalRecords := len(matched)
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	start := filters.Offset()
+	if start > totalRecords {
+		start = totalRecords
+	}
+	end := start + filters.Limit()
+	if end > totalRecords {
+		end = totalRecords
+	}
+
+	return matched[start:end], metadata, nil
+}
+
+// Restore implements domain.ContactRepository
+func (repository *MemoryContactRepository) Restore(ctx context.Context, id int64, ownerID int64) (*domain.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	contact, ok := store.contacts[id]
+	if !ok || contact.OwnerID != ownerID || contact.DeletedAt == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	before := contact
+	contact.DeletedAt = nil
+	contact.Version++
+
+	rollback := store.savepoint()
+
+	err := store.recordHistory(ctx, domain.EntityContact, id, ownerID, contact.Version, domain.HistoryRestored, before, contact)
+	if err == nil {
+		err = store.recordEvent(ctx, ownerID, domain.ContactRestored{Contact: contact})
+	}
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+
+	store.contacts[id] = contact
+	contact = cloneContact(contact)
+	return &contact, nil
+}
+
+// Purge implements domain.ContactRepository. The history of the purged
+// contacts is kept.
+func (repository *MemoryContactRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var purged int64
+	for id, contact := range store.contacts {
+		if contact.DeletedAt == nil || !contact.DeletedAt.Before(deletedBefore) {
+			continue
+		}
+
+		delete(store.contacts, id)
+		for _, members := range store.members {
+			delete(members, id)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// ListMissingPhoneE164 implements domain.ContactRepository
+func (repository *MemoryContactRepository) ListMissingPhoneE164(ctx context.Context, afterID int64, limit int) ([]*domain.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	contacts := []*domain.Contact{}
+	for id, contact := range store.contacts {
+		if id <= afterID || !missingPhoneE164(contact) {
+			continue
+		}
+		clone := cloneContact(contact)
+		contacts = append(contacts, &clone)
+	}
+
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].ID < contacts[j].ID
+	})
+	if len(contacts) > limit {
+		contacts = contacts[:limit]
+	}
+	return contacts, nil
+}
+
+func missingPhoneE164(contact domain.Contact) bool {
+	if contact.Phone != "" && contact.PhoneE164 == "" {
+		return true
+	}
+	for _, phone := range contact.Phones {
+		if phone.E164 == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPhoneE164 implements domain.ContactRepository
+func (repository *MemoryContactRepository) SetPhoneE164(ctx context.Context, contact *domain.Contact) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	stored, ok := store.contacts[contact.ID]
+	if !ok || stored.Version != contact.Version {
+		return ErrEditConflict
+	}
+
+	stored.PhoneE164 = contact.PhoneE164
+	stored.Phones = append([]domain.PhoneNumber(nil), contact.Phones...)
+	store.contacts[contact.ID] = stored
+	return nil
+}
+
+// Merge implements domain.ContactRepository
+func (repository *MemoryContactRepository) Merge(ctx context.Context, id int64, duplicateID int64, ownerID int64) (*domain.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	before, ok := store.contacts[id]
+	if !ok || before.OwnerID != ownerID || before.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+	duplicate, ok := store.contacts[duplicateID]
+	if !ok || duplicate.OwnerID != ownerID || duplicate.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	contact := cloneContact(before)
+	domain.MergeContacts(&contact, &duplicate)
+	contact.Version++
+
+	deleted := duplicate
+	deletedAt := now()
+	deleted.DeletedAt = &deletedAt
+	deleted.Version++
+
+	groupIDs := []int64{}
+	for groupID, members := range store.members {
+		if _, ok := members[duplicateID]; ok {
+			groupIDs = append(groupIDs, groupID)
+		}
+	}
+	sort.Slice(groupIDs, func(i, j int) bool {
+		return groupIDs[i] < groupIDs[j]
+	})
+
+	rollback := store.savepoint()
+
+	err := store.recordHistory(ctx, domain.EntityContact, id, ownerID, contact.Version, domain.HistoryMerged, before, contact)
+	if err == nil {
+		err = store.recordEvent(ctx, ownerID, domain.ContactUpdated{Contact: contact})
+	}
+	for _, groupID := range groupIDs {
+		if err == nil {
+			err = store.recordEvent(ctx, ownerID, domain.GroupMemberRemoved{GroupID: groupID, ContactID: duplicateID})
+		}
+		if _, ok := store.members[groupID][id]; !ok && err == nil {
+			err = store.recordEvent(ctx, ownerID, domain.GroupMemberAdded{GroupID: groupID, ContactID: id})
+		}
+	}
+	if err == nil {
+		err = store.recordHistory(ctx, domain.EntityContact, duplicateID, ownerID, deleted.Version, domain.HistoryDeleted, duplicate, deleted)
+	}
+	if err == nil {
+		err = store.recordEvent(ctx, ownerID, domain.ContactDeleted{ContactID: duplicateID})
+	}
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+
+	for _, groupID := range groupIDs {
+		members := store.members[groupID]
+		delete(members, duplicateID)
+		if _, ok := members[id]; !ok {
+			members[id] = now()
+		}
+	}
+
+	store.contacts[id] = contact
+	store.contacts[duplicateID] = deleted
+	contact = cloneContact(contact)
+	return &contact, nil
+}
+
+// matchesFullName approximates the SQL search: either every word of the query
+// occurs in the name, or the whole query is a case-insensitive substring.
+func matchesFullName(fullName string, query string) bool {
+	if query == "" {
+		return true
+	}
+
+	name := strings.ToLower(fullName)
+	if strings.Contains(name, strings.ToLower(query)) {
+		return true
+	}
+
+	words := strings.Fields(name)
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		found := false
+		for _, word := range words {
+			if word == term {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func NewMemoryContactRepository(store *MemoryStore) domain.ContactRepository {
+	return &MemoryContactRepository{store}
+}
@@ -0,0 +1,419 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type MemoryGroupRepository struct {
+	store *MemoryStore
+}
+
+// Create implements domain.GroupRepository
+func (repository *MemoryGroupRepository) Create(ctx context.Context, group *domain.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if group.ParentID != nil {
+		if parent, ok := store.groups[*group.ParentID]; !ok || parent.OwnerID != group.OwnerID {
+			return ErrInvalidParent
+		}
+	}
+	if store.hasGroupName(group.OwnerID, group.GroupName, 0) {
+		return ErrDuplicateGroupName
+	}
+
+	store.lastGroupID++
+	group.ID = store.lastGroupID
+	group.CreatedAt = now()
+	group.Version = 1
+
+	err := store.recordHistory(ctx, domain.EntityGroup, group.ID, group.OwnerID, group.Version, domain.HistoryCreated, nil, group)
+	if err != nil {
+		return err
+	}
+
+	store.groups[group.ID] = *group
+	return nil
+}
+
+// GetByID implements domain.GroupRepository
+func (repository *MemoryGroupRepository) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	group, ok := store.groups[id]
+	if !ok || group.OwnerID != ownerID {
+		return nil, ErrRecordNotFound
+	}
+	return &group, nil
+}
+
+// Update implements domain.GroupRepository
+func (repository *MemoryGroupRepository) Update(ctx context.Context, group *domain.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, ok := store.groups[group.ID]
+	if !ok || existing.OwnerID != group.OwnerID || existing.Version != group.Version {
+		return ErrEditConflict
+	}
+	if store.hasGroupName(group.OwnerID, group.GroupName, group.ID) {
+		return ErrDuplicateGroupName
+	}
+
+	after := *group
+	after.ParentID = existing.ParentID
+	after.CreatedAt = existing.CreatedAt
+	after.Version++
+
+	rollback := store.savepoint()
+
+	err := store.recordHistory(ctx, domain.EntityGroup, after.ID, after.OwnerID, after.Version, domain.HistoryUpdated, existing, after)
+	if err == nil && after.GroupName != existing.GroupName {
+		err = store.recordEvent(ctx, after.OwnerID, domain.GroupRenamed{GroupID: after.ID, OldName: existing.GroupName, NewName: after.GroupName})
+	}
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	*group = after
+	store.groups[group.ID] = after
+	return nil
+}
+
+// Delete implements domain.GroupRepository
+func (repository *MemoryGroupRepository) Delete(ctx context.Context, id int64, ownerID int64, policy domain.GroupDeletePolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if group, ok := store.groups[id]; !ok || group.OwnerID != ownerID {
+		return ErrRecordNotFound
+	}
+
+	subtree := store.subtree(id)
+
+	if policy == domain.GroupDeleteRestrict {
+		if len(subtree) > 1 {
+			return ErrGroupNotEmpty
+		}
+		for contactID := range store.members[id] {
+			if store.contacts[contactID].DeletedAt == nil {
+				return ErrGroupNotEmpty
+			}
+		}
+	}
+
+	// Every group of the subtree is recorded as deleted, after the removal
+	// of its members, trashed contacts included.
+	rollback := store.savepoint()
+	for _, groupID := range subtree {
+		contactIDs := make([]int64, 0, len(store.members[groupID]))
+		for contactID := range store.members[groupID] {
+			contactIDs = append(contactIDs, contactID)
+		}
+		sort.Slice(contactIDs, func(i, j int) bool {
+			return contactIDs[i] < contactIDs[j]
+		})
+
+		for _, contactID := range contactIDs {
+			err := store.recordEvent(ctx, ownerID, domain.GroupMemberRemoved{GroupID: groupID, ContactID: contactID})
+			if err != nil {
+				rollback()
+				return err
+			}
+		}
+	}
+	for _, groupID := range subtree {
+		group := store.groups[groupID]
+		err := store.recordHistory(ctx, domain.EntityGroup, groupID, ownerID, group.Version+1, domain.HistoryDeleted, group, nil)
+		if err == nil {
+			err = store.recordEvent(ctx, ownerID, domain.GroupDeleted{GroupID: groupID})
+		}
+		if err != nil {
+			rollback()
+			return err
+		}
+	}
+
+	for _, groupID := range subtree {
+		delete(store.groups, groupID)
+		delete(store.members, groupID)
+	}
+	return nil
+}
+
+// Move implements domain.GroupRepository
+func (repository *MemoryGroupRepository) Move(ctx context.Context, id int64, parentID *int64, ownerID int64) (*domain.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	group, ok := store.groups[id]
+	if !ok || group.OwnerID != ownerID {
+		return nil, ErrRecordNotFound
+	}
+
+	if parentID != nil {
+		if parent, ok := store.groups[*parentID]; !ok || parent.OwnerID != ownerID {
+			return nil, ErrInvalidParent
+		}
+		for _, groupID := range store.subtree(id) {
+			if groupID == *parentID {
+				return nil, ErrInvalidParent
+			}
+		}
+	}
+
+	before := group
+	group.ParentID = parentID
+	group.Version++
+
+	err := store.recordHistory(ctx, domain.EntityGroup, id, ownerID, group.Version, domain.HistoryUpdated, before, group)
+	if err != nil {
+		return nil, err
+	}
+
+	store.groups[id] = group
+	return &group, nil
+}
+
+// Ancestors implements domain.GroupRepository
+func (repository *MemoryGroupRepository) Ancestors(ctx context.Context, id int64, ownerID int64) ([]*domain.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	group, ok := store.groups[id]
+	if !ok || group.OwnerID != ownerID {
+		return nil, ErrRecordNotFound
+	}
+
+	groups := []*domain.Group{}
+	for group.ParentID != nil {
+		group = store.groups[*group.ParentID]
+		parent := group
+		groups = append(groups, &parent)
+	}
+	return groups, nil
+}
+
+// Descendants implements domain.GroupRepository
+func (repository *MemoryGroupRepository) Descendants(ctx context.Context, id int64, ownerID int64) ([]*domain.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if group, ok := store.groups[id]; !ok || group.OwnerID != ownerID {
+		return nil, ErrRecordNotFound
+	}
+
+	groups := []*domain.Group{}
+	for _, groupID := range store.subtree(id)[1:] {
+		group := store.groups[groupID]
+		groups = append(groups, &group)
+	}
+	return groups, nil
+}
+
+// AddMember implements domain.GroupRepository
+func (repository *MemoryGroupRepository) AddMember(ctx context.Context, member *domain.GroupMember, ownerID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	group, groupExists := store.groups[member.GroupID]
+	contact, contactExists := store.contacts[member.ContactID]
+	if !groupExists || !contactExists || group.OwnerID != ownerID || contact.OwnerID != ownerID || contact.DeletedAt != nil {
+		return ErrRecordNotFound
+	}
+
+	members, ok := store.members[member.GroupID]
+	if !ok {
+		members = make(map[int64]time.Time)
+		store.members[member.GroupID] = members
+	}
+	if _, ok := members[member.ContactID]; ok {
+		return ErrDuplicateMember
+	}
+
+	err := store.recordEvent(ctx, ownerID, domain.GroupMemberAdded{GroupID: member.GroupID, ContactID: member.ContactID})
+	if err != nil {
+		return err
+	}
+
+	member.CreatedAt = now()
+	members[member.ContactID] = member.CreatedAt
+	return nil
+}
+
+// RemoveMember implements domain.GroupRepository
+func (repository *MemoryGroupRepository) RemoveMember(ctx context.Context, groupID int64, contactID int64, ownerID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if group, ok := store.groups[groupID]; !ok || group.OwnerID != ownerID {
+		return ErrRecordNotFound
+	}
+
+	members := store.members[groupID]
+	if _, ok := members[contactID]; !ok {
+		return ErrRecordNotFound
+	}
+
+	err := store.recordEvent(ctx, ownerID, domain.GroupMemberRemoved{GroupID: groupID, ContactID: contactID})
+	if err != nil {
+		return err
+	}
+
+	delete(members, contactID)
+	return nil
+}
+
+// ListMembers implements domain.GroupRepository
+func (repository *MemoryGroupRepository) ListMembers(ctx context.Context, groupID int64, ownerID int64, includeSubgroups bool) ([]*domain.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if group, ok := store.groups[groupID]; !ok || group.OwnerID != ownerID {
+		return nil, ErrRecordNotFound
+	}
+
+	groupIDs := []int64{groupID}
+	if includeSubgroups {
+		groupIDs = store.subtree(groupID)
+	}
+
+	seen := make(map[int64]bool)
+	contacts := []*domain.Contact{}
+	for _, groupID := range groupIDs {
+		for contactID := range store.members[groupID] {
+			contact := store.contacts[contactID]
+			if contact.DeletedAt != nil || seen[contactID] {
+				continue
+			}
+			seen[contactID] = true
+			contacts = append(contacts, &contact)
+		}
+	}
+
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].ID < contacts[j].ID
+	})
+	return contacts, nil
+}
+
+// Export implements domain.GroupRepository
+func (repository *MemoryGroupRepository) Export(ctx context.Context, ownerID int64) (domain.Cursor[domain.Group], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	groups := []domain.Group{}
+	for _, group := range store.groups {
+		if group.OwnerID == ownerID {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+	return newSliceCursor(groups), nil
+}
+
+// hasGroupName reports whether ownerID has a group other than exceptID named
+// groupName, ignoring case. The caller must hold store.mu.
+func (store *MemoryStore) hasGroupName(ownerID int64, groupName string, exceptID int64) bool {
+	for id, group := range store.groups {
+		if id != exceptID && group.OwnerID == ownerID && strings.EqualFold(group.GroupName, groupName) {
+			return true
+		}
+	}
+	return false
+}
+
+// subtree returns id followed by the IDs of its descendants, breadth first
+// and ordered by ID within each level. The caller must hold store.mu.
+func (store *MemoryStore) subtree(id int64) []int64 {
+	ids := []int64{id}
+	for level := ids; len(level) > 0; {
+		parents := make(map[int64]bool, len(level))
+		for _, parentID := range level {
+			parents[parentID] = true
+		}
+
+		var children []int64
+		for groupID, group := range store.groups {
+			if group.ParentID != nil && parents[*group.ParentID] {
+				children = append(children, groupID)
+			}
+		}
+		sort.Slice(children, func(i, j int) bool {
+			return children[i] < children[j]
+		})
+
+		ids = append(ids, children...)
+		level = children
+	}
+	return ids
+}
+
+func NewMemoryGroupRepository(store *MemoryStore) domain.GroupRepository {
+	return &MemoryGroupRepository{store}
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type MemoryHistoryRepository struct {
+	store *MemoryStore
+}
+
+// List implements domain.HistoryRepository
+func (repository *MemoryHistoryRepository) List(ctx context.Context, entityType domain.EntityType, entityID int64, ownerID int64, filters domain.Filters) ([]*domain.HistoryEntry, domain.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	matched := []*domain.HistoryEntry{}
+	for _, entry := range store.history {
+		entry := entry
+		if entry.EntityType == entityType && entry.EntityID == entityID && entry.OwnerID == ownerID {
+			matched = append(matched, &entry)
+		}
+	}
+	store.mu.RUnlock()
+
+	if len(matched) == 0 {
+		return nil, domain.Metadata{}, ErrRecordNotFound
+	}
+
+	// Entries are appended in version order.
+	if filters.SortDirection() == "DESC" {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+
+	totalRecords := len(matched)
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	start := filters.Offset()
+	if start > totalRecords {
+		start = totalRecords
+	}
+	end := start + filters.Limit()
+	if end > totalRecords {
+		end = totalRecords
+	}
+
+	return matched[start:end], metadata, nil
+}
+
+// GetVersion implements domain.HistoryRepository
+func (repository *MemoryHistoryRepository) GetVersion(ctx context.Context, entityType domain.EntityType, entityID int64, ownerID int64, version int32) (*domain.HistoryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, entry := range store.history {
+		if entry.EntityType == entityType && entry.EntityID == entityID && entry.OwnerID == ownerID && entry.Version == version {
+			return &entry, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func NewMemoryHistoryRepository(store *MemoryStore) domain.HistoryRepository {
+	return &MemoryHistoryRepository{store}
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type outboxEntry struct {
+	event         domain.Event
+	attempts      int32
+	lastError     string
+	failed        bool
+	nextAttemptAt time.Time
+}
+
+type MemoryOutboxRepository struct {
+	store *MemoryStore
+}
+
+// Dispatch implements domain.OutboxRepository. The store is not locked while
+// publishing, so that publishers may use the other repositories.
+func (repository *MemoryOutboxRepository) Dispatch(ctx context.Context, limit int, policy domain.OutboxPolicy, publish func(event *domain.Event) error) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	events := make([]domain.Event, 0, limit)
+	due := now()
+	for i := 0; i < len(store.outbox) && len(events) < limit; i++ {
+		entry := store.outbox[i]
+		if !entry.failed && !entry.nextAttemptAt.After(due) {
+			events = append(events, entry.event)
+		}
+	}
+	store.mu.RUnlock()
+
+	published := 0
+	var publishErr error
+
+	for i := range events {
+		event := &events[i]
+
+		if err := publish(event); err != nil {
+			store.mu.Lock()
+			if entry := store.outboxEntry(event.ID); entry != nil {
+				entry.attempts++
+				entry.lastError = err.Error()
+				entry.failed = entry.attempts >= policy.MaxAttempts
+				entry.nextAttemptAt = now().Add(policy.RetryDelay(entry.attempts))
+			}
+			store.mu.Unlock()
+
+			if publishErr == nil {
+				publishErr = fmt.Errorf("publishing event %d: %w", event.ID, err)
+			}
+			continue
+		}
+
+		store.mu.Lock()
+		for j := range store.outbox {
+			if store.outbox[j].event.ID == event.ID {
+				store.outbox = append(store.outbox[:j], store.outbox[j+1:]...)
+				break
+			}
+		}
+		store.mu.Unlock()
+		published++
+	}
+
+	return published, publishErr
+}
+
+// recordEvent appends payload to the outbox. The caller must hold the write
+// lock, as for recordHistory.
+func (store *MemoryStore) recordEvent(ctx context.Context, ownerID int64, payload domain.DomainEvent) error {
+	event, err := domain.NewEvent(ctx, ownerID, payload)
+	if err != nil {
+		return err
+	}
+
+	store.lastEventID++
+	event.ID = store.lastEventID
+	event.CreatedAt = now()
+
+	store.outbox = append(store.outbox, outboxEntry{event: *event, nextAttemptAt: event.CreatedAt})
+	return nil
+}
+
+// outboxEntry returns the pending entry of the event with the given ID, or
+// nil. The caller must hold store.mu.
+func (store *MemoryStore) outboxEntry(id int64) *outboxEntry {
+	for i := range store.outbox {
+		if store.outbox[i].event.ID == id {
+			return &store.outbox[i]
+		}
+	}
+	return nil
+}
+
+func NewMemoryOutboxRepository(store *MemoryStore) domain.OutboxRepository {
+	return &MemoryOutboxRepository{store}
+}
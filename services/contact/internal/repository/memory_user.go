@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type MemoryUserRepository struct {
+	store *MemoryStore
+}
+
+// Create implements domain.UserRepository
+func (repository *MemoryUserRepository) Create(ctx context.Context, user *domain.User, permissions domain.Permissions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, existing := range store.users {
+		if existing.Email == user.Email {
+			return ErrDuplicateEmail
+		}
+	}
+
+	store.lastUserID++
+	user.ID = store.lastUserID
+	user.CreatedAt = now()
+	user.Version = 1
+
+	store.users[user.ID] = *user
+
+	store.permissions[user.ID] = make(map[string]bool)
+	for _, code := range permissions {
+		store.permissions[user.ID][code] = true
+	}
+	return nil
+}
+
+// GetByEmail implements domain.UserRepository
+func (repository *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, user := range store.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+// GetForToken implements domain.UserRepository
+func (repository *MemoryUserRepository) GetForToken(ctx context.Context, scope string, tokenPlaintext string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	token, ok := store.tokens[string(domain.HashToken(tokenPlaintext))]
+	if !ok || token.Scope != scope || !token.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	user, ok := store.users[token.UserID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func NewMemoryUserRepository(store *MemoryStore) domain.UserRepository {
+	return &MemoryUserRepository{store}
+}
+
+type MemoryTokenRepository struct {
+	store *MemoryStore
+}
+
+// Create implements domain.TokenRepository
+func (repository *MemoryTokenRepository) Create(ctx context.Context, token *domain.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.tokens[string(token.Hash)] = *token
+	return nil
+}
+
+// DeleteAllForUser implements domain.TokenRepository
+func (repository *MemoryTokenRepository) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for hash, token := range store.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(store.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func NewMemoryTokenRepository(store *MemoryStore) domain.TokenRepository {
+	return &MemoryTokenRepository{store}
+}
+
+type MemoryPermissionRepository struct {
+	store *MemoryStore
+}
+
+// GetAllForUser implements domain.PermissionRepository
+func (repository *MemoryPermissionRepository) GetAllForUser(ctx context.Context, userID int64) (domain.Permissions, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var permissions domain.Permissions
+	for code := range store.permissions[userID] {
+		permissions = append(permissions, code)
+	}
+	sort.Strings(permissions)
+
+	return permissions, nil
+}
+
+func NewMemoryPermissionRepository(store *MemoryStore) domain.PermissionRepository {
+	return &MemoryPermissionRepository{store}
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type MemoryWebhookRepository struct {
+	store *MemoryStore
+}
+
+// Create implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.lastWebhookID++
+	webhook.ID = store.lastWebhookID
+	webhook.Active = true
+	webhook.Failures = 0
+	webhook.DisabledAt = nil
+	webhook.CreatedAt = now()
+	webhook.Version = 1
+
+	store.webhooks[webhook.ID] = cloneWebhook(*webhook)
+	return nil
+}
+
+// GetByID implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	webhook, ok := store.webhooks[id]
+	if !ok || webhook.OwnerID != ownerID {
+		return nil, ErrRecordNotFound
+	}
+
+	webhook = cloneWebhook(webhook)
+	return &webhook, nil
+}
+
+// List implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) List(ctx context.Context, ownerID int64) ([]*domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	webhooks := []*domain.Webhook{}
+	for _, webhook := range store.webhooks {
+		if webhook.OwnerID == ownerID {
+			webhook := cloneWebhook(webhook)
+			webhooks = append(webhooks, &webhook)
+		}
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+// Update implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) Update(ctx context.Context, webhook *domain.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, ok := store.webhooks[webhook.ID]
+	if !ok || existing.OwnerID != webhook.OwnerID || existing.Version != webhook.Version {
+		return ErrEditConflict
+	}
+
+	webhook.Failures = existing.Failures
+	webhook.DisabledAt = existing.DisabledAt
+	switch {
+	case webhook.Active && !existing.Active:
+		webhook.Failures = 0
+		webhook.DisabledAt = nil
+	case !webhook.Active && existing.DisabledAt == nil:
+		disabledAt := now()
+		webhook.DisabledAt = &disabledAt
+	}
+	webhook.CreatedAt = existing.CreatedAt
+	webhook.Version++
+
+	store.webhooks[webhook.ID] = cloneWebhook(*webhook)
+	return nil
+}
+
+// Delete implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) Delete(ctx context.Context, id int64, ownerID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	webhook, ok := store.webhooks[id]
+	if !ok || webhook.OwnerID != ownerID {
+		return ErrRecordNotFound
+	}
+
+	delete(store.webhooks, id)
+	for deliveryID, delivery := range store.deliveries {
+		if delivery.WebhookID == id {
+			delete(store.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// ListDeliveries implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) ListDeliveries(ctx context.Context, webhookID int64, ownerID int64, filters domain.Filters) ([]*domain.WebhookDelivery, domain.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	store := repository.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if webhook, ok := store.webhooks[webhookID]; !ok || webhook.OwnerID != ownerID {
+		return nil, domain.Metadata{}, ErrRecordNotFound
+	}
+
+	matched := []*domain.WebhookDelivery{}
+	for _, delivery := range store.deliveries {
+		if delivery.WebhookID == webhookID {
+			delivery := delivery
+			delivery.Log = append([]domain.WebhookAttempt{}, delivery.Log...)
+			matched = append(matched, &delivery)
+		}
+	}
+
+	desc := filters.SortDirection() == "DESC"
+	sort.Slice(matched, func(i, j int) bool {
+		if desc {
+			return matched[i].ID > matched[j].ID
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	totalRecords := len(matched)
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	start := filters.Offset()
+	if start > totalRecords {
+		start = totalRecords
+	}
+	end := start + filters.Limit()
+	if end > totalRecords {
+		end = totalRecords
+	}
+
+	return matched[start:end], metadata, nil
+}
+
+// Enqueue implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) Enqueue(ctx context.Context, event *domain.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	enqueued := make(map[int64]bool)
+	for _, delivery := range store.deliveries {
+		if delivery.EventID == event.ID {
+			enqueued[delivery.WebhookID] = true
+		}
+	}
+
+	for _, webhook := range store.webhooks {
+		if webhook.OwnerID != event.OwnerID || !webhook.Active || !webhook.Subscribed(event.Type) || enqueued[webhook.ID] {
+			continue
+		}
+
+		store.lastDeliveryID++
+		createdAt := now()
+		store.deliveries[store.lastDeliveryID] = domain.WebhookDelivery{
+			ID:            store.lastDeliveryID,
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: createdAt,
+			CreatedAt:     createdAt,
+		}
+	}
+	return nil
+}
+
+// Claim implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current := time.Now()
+
+	due := []domain.WebhookDelivery{}
+	for _, delivery := range store.deliveries {
+		if delivery.Status == domain.DeliveryPending && !delivery.NextAttemptAt.After(current) && store.webhooks[delivery.WebhookID].Active {
+			due = append(due, delivery)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	jobs := []*domain.WebhookJob{}
+	for _, delivery := range due {
+		delivery.NextAttemptAt = current.Add(lease)
+		store.deliveries[delivery.ID] = delivery
+
+		delivery.Log = nil
+		jobs = append(jobs, &domain.WebhookJob{Delivery: delivery, Webhook: cloneWebhook(store.webhooks[delivery.WebhookID])})
+	}
+	return jobs, nil
+}
+
+// Complete implements domain.WebhookRepository
+func (repository *MemoryWebhookRepository) Complete(ctx context.Context, delivery *domain.WebhookDelivery, attempt *domain.WebhookAttempt, disableAfter int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store := repository.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, ok := store.deliveries[delivery.ID]
+	if !ok {
+		// The webhook was deleted meanwhile.
+		return nil
+	}
+
+	attempt.CreatedAt = now()
+
+	existing.Status = delivery.Status
+	existing.Attempts = delivery.Attempts
+	existing.NextAttemptAt = delivery.NextAttemptAt
+	existing.Log = append(existing.Log, *attempt)
+	store.deliveries[delivery.ID] = existing
+
+	webhook := store.webhooks[delivery.WebhookID]
+	switch delivery.Status {
+	case domain.DeliverySucceeded:
+		webhook.Failures = 0
+	case domain.DeliveryFailed:
+		webhook.Failures++
+		if webhook.Active && webhook.Failures >= disableAfter {
+			disabledAt := now()
+			webhook.Active = false
+			webhook.DisabledAt = &disabledAt
+			webhook.Version++
+		}
+	}
+
+	store.webhooks[delivery.WebhookID] = webhook
+	return nil
+}
+
+// cloneWebhook copies the event list of webhook, like cloneContact.
+func cloneWebhook(webhook domain.Webhook) domain.Webhook {
+	webhook.Events = append([]domain.EventType{}, webhook.Events...)
+	return webhook
+}
+
+func NewMemoryWebhookRepository(store *MemoryStore) domain.WebhookRepository {
+	return &MemoryWebhookRepository{store}
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type SQLOutboxRepository struct {
+	DB *sql.DB
+}
+
+// Dispatch implements domain.OutboxRepository. The events stay locked until
+// the batch is done, and locked events are skipped, so that concurrent relays
+// never publish the same event twice.
+func (repository *SQLOutboxRepository) Dispatch(ctx context.Context, limit int, policy domain.OutboxPolicy, publish func(event *domain.Event) error) (int, error) {
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, event_type, owner_id, actor_id, payload, created_at, attempts
+		FROM outbox
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	events := []*domain.Event{}
+	attempts := []int32{}
+
+	for rows.Next() {
+		var event domain.Event
+		var attempt int32
+
+		err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.OwnerID,
+			&event.ActorID,
+			(*[]byte)(&event.Payload),
+			&event.CreatedAt,
+			&attempt,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		events = append(events, &event)
+		attempts = append(attempts, attempt)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var publishErr error
+
+	for i, event := range events {
+		if err := publish(event); err != nil {
+			attempt := attempts[i] + 1
+			status := "pending"
+			if attempt >= policy.MaxAttempts {
+				status = "failed"
+			}
+
+			query = `
+				UPDATE outbox
+				SET attempts = $2, last_error = $3, status = $4, next_attempt_at = $5
+				WHERE id = $1`
+
+			args := []any{event.ID, attempt, err.Error(), status, time.Now().Add(policy.RetryDelay(attempt))}
+
+			_, updateErr := tx.ExecContext(ctx, query, args...)
+			if updateErr != nil {
+				return published, updateErr
+			}
+
+			if publishErr == nil {
+				publishErr = fmt.Errorf("publishing event %d: %w", event.ID, err)
+			}
+			continue
+		}
+
+		query = `
+			DELETE FROM outbox
+			WHERE id = $1`
+
+		_, err = tx.ExecContext(ctx, query, event.ID)
+		if err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return published, err
+	}
+
+	return published, publishErr
+}
+
+// recordEvent writes payload to the outbox within tx, the transaction of the
+// change it describes.
+func recordEvent(ctx context.Context, tx *sql.Tx, ownerID int64, payload domain.DomainEvent) error {
+	event, err := domain.NewEvent(ctx, ownerID, payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox (event_type, owner_id, actor_id, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	args := []any{event.Type, event.OwnerID, event.ActorID, jsonbArg(event.Payload)}
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.CreatedAt)
+}
+
+func NewOutboxRepository(conn *sql.DB) domain.OutboxRepository {
+	return &SQLOutboxRepository{conn}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type SQLPermissionRepository struct {
+	DB *sql.DB
+}
+
+// GetAllForUser implements domain.PermissionRepository
+func (repository *SQLPermissionRepository) GetAllForUser(ctx context.Context, userID int64) (domain.Permissions, error) {
+	query := `
+		SELECT p.code
+		FROM permissions p
+		INNER JOIN users_permissions up ON up.permission_id = p.id
+		WHERE up.user_id = $1
+		ORDER BY p.code`
+
+	rows, err := repository.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions domain.Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func NewPermissionRepository(conn *sql.DB) domain.PermissionRepository {
+	return &SQLPermissionRepository{conn}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type SQLTokenRepository struct {
+	DB *sql.DB
+}
+
+// Create implements domain.TokenRepository
+func (repository *SQLTokenRepository) Create(ctx context.Context, token *domain.Token) error {
+	query := `
+		INSERT INTO tokens (hash, user_id, expiry, scope)
+		VALUES ($1, $2, $3, $4)`
+
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+
+	_, err := repository.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteAllForUser implements domain.TokenRepository
+func (repository *SQLTokenRepository) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	query := `
+		DELETE FROM tokens
+		WHERE scope = $1 AND user_id = $2`
+
+	_, err := repository.DB.ExecContext(ctx, query, scope, userID)
+	return err
+}
+
+func NewTokenRepository(conn *sql.DB) domain.TokenRepository {
+	return &SQLTokenRepository{conn}
+}
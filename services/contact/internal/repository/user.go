@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+	"github.com/lib/pq"
+)
+
+type SQLUserRepository struct {
+	DB *sql.DB
+}
+
+// Create implements domain.UserRepository. The user and its permissions are
+// inserted by one statement.
+func (repository *SQLUserRepository) Create(ctx context.Context, user *domain.User, permissions domain.Permissions) error {
+	query := `
+		WITH new_user AS (
+			INSERT INTO users (name, email, password_hash)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at, version
+		), granted AS (
+			INSERT INTO users_permissions (user_id, permission_id)
+			SELECT new_user.id, p.id FROM new_user CROSS JOIN permissions p
+			WHERE p.code = ANY($4)
+		)
+		SELECT id, created_at, version FROM new_user`
+
+	args := []any{user.Name, user.Email, user.Password.Hash(), pq.Array(permissions)}
+
+	err := repository.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByEmail implements domain.UserRepository
+func (repository *SQLUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	query := `
+		SELECT id, created_at, name, email, password_hash, version
+		FROM users
+		WHERE email = $1`
+
+	var (
+		user domain.User
+		hash []byte
+	)
+
+	err := repository.DB.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&hash,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	user.Password.SetHash(hash)
+	return &user, nil
+}
+
+// GetForToken implements domain.UserRepository
+func (repository *SQLUserRepository) GetForToken(ctx context.Context, scope string, tokenPlaintext string) (*domain.User, error) {
+	query := `
+		SELECT u.id, u.created_at, u.name, u.email, u.password_hash, u.version
+		FROM users u
+		INNER JOIN tokens t ON t.user_id = u.id
+		WHERE t.hash = $1
+		AND t.scope = $2
+		AND t.expiry > $3`
+
+	args := []any{domain.HashToken(tokenPlaintext), scope, time.Now()}
+
+	var (
+		user domain.User
+		hash []byte
+	)
+
+	err := repository.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&hash,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	user.Password.SetHash(hash)
+	return &user, nil
+}
+
+func NewUserRepository(conn *sql.DB) domain.UserRepository {
+	return &SQLUserRepository{conn}
+}
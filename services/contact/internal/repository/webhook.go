@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+	"github.com/lib/pq"
+)
+
+type SQLWebhookRepository struct {
+	DB *sql.DB
+}
+
+// Create implements domain.WebhookRepository
+func (repository *SQLWebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	query := `
+		INSERT INTO webhooks (owner_id, url, events, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, active, failures, created_at, version`
+
+	args := []any{webhook.OwnerID, webhook.URL, eventTypesColumn{&webhook.Events}, webhook.Secret}
+
+	return repository.DB.QueryRowContext(ctx, query, args...).Scan(
+		&webhook.ID,
+		&webhook.Active,
+		&webhook.Failures,
+		&webhook.CreatedAt,
+		&webhook.Version,
+	)
+}
+
+// GetByID implements domain.WebhookRepository
+func (repository *SQLWebhookRepository) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Webhook, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, owner_id, url, events, secret, active, failures, disabled_at, created_at, version
+		FROM webhooks
+		WHERE id = $1 AND owner_id = $2`
+
+	var webhook domain.Webhook
+
+	err := repository.DB.QueryRowContext(ctx, query, id, ownerID).Scan(webhookFields(&webhook)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &webhook, nil
+}
+
+// List implements domain.WebhookRepository
+func (repository *SQLWebhookRepository) List(ctx context.Context, ownerID int64) ([]*domain.Webhook, error) {
+	query := `
+		SELECT id, owner_id, url, events, secret, active, failures, disabled_at, created_at, version
+		FROM webhooks
+		WHERE owner_id = $1
+		ORDER BY id`
+
+	rows, err := repository.DB.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*domain.Webhook{}
+
+	for rows.Next() {
+		var webhook domain.Webhook
+
+		err := rows.Scan(webhookFields(&webhook)...)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Update implements domain.WebhookRepository. Enabling a webhook clears its
+// failures, disabling it records when.
+func (repository *SQLWebhookRepository) Update(ctx context.Context, webhook *domain.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = $1, events = $2, secret = $3, active = $4,
+		    failures = CASE WHEN $4 AND NOT active THEN 0 ELSE failures END,
+		    disabled_at = CASE WHEN $4 THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+		    version = version + 1
+		WHERE id = $5 AND owner_id = $6 AND version = $7
+		RETURNING failures, disabled_at, version`
+
+	args := []any{
+		webhook.URL,
+		eventTypesColumn{&webhook.Events},
+		webhook.Secret,
+		webhook.Active,
+		webhook.ID,
+		webhook.OwnerID,
+		webhook.Version,
+	}
+
+	err := repository.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.Failures, &webhook.DisabledAt, &webhook.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete implements domain.WebhookRepository. The deliveries of the webhook
+// are removed by the foreign key.
+func (repository *SQLWebhookRepository) Delete(ctx context.Context, id int64, ownerID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND owner_id = $2`
+
+	result, err := repository.DB.ExecContext(ctx, query, id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ListDeliveries implements domain.WebhookRepository
+func (repository *SQLWebhookRepository) ListDeliveries(ctx context.Context, webhookID int64, ownerID int64, filters domain.Filters) ([]*domain.WebhookDelivery, domain.Metadata, error) {
+	if webhookID < 1 {
+		return nil, domain.Metadata{}, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM webhooks WHERE id = $1 AND owner_id = $2)`
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, webhookID, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+	if !exists {
+		return nil, domain.Metadata{}, ErrRecordNotFound
+	}
+
+	query = fmt.Sprintf(`
+		SELECT count(*) OVER(), id, webhook_id, event_id, event_type, payload, status,
+		       attempts, next_attempt_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY %s %s
+		LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
+
+	rows, err := repository.DB.QueryContext(ctx, query, webhookID, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	deliveries := []*domain.WebhookDelivery{}
+	byID := make(map[int64]*domain.WebhookDelivery)
+	ids := []int64{}
+
+	for rows.Next() {
+		var delivery domain.WebhookDelivery
+
+		err := rows.Scan(append([]any{&totalRecords}, deliveryFields(&delivery)...)...)
+		if err != nil {
+			return nil, domain.Metadata{}, err
+		}
+
+		delivery.Log = []domain.WebhookAttempt{}
+		deliveries = append(deliveries, &delivery)
+		byID[delivery.ID] = &delivery
+		ids = append(ids, delivery.ID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	query = `
+		SELECT delivery_id, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY delivery_id, attempt`
+
+	attemptRows, err := repository.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+	defer attemptRows.Close()
+
+	for attemptRows.Next() {
+		var deliveryID int64
+		var attempt domain.WebhookAttempt
+
+		err := attemptRows.Scan(
+			&deliveryID,
+			&attempt.Attempt,
+			&attempt.StatusCode,
+			&attempt.Error,
+			&attempt.DurationMS,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, domain.Metadata{}, err
+		}
+
+		delivery := byID[deliveryID]
+		delivery.Log = append(delivery.Log, attempt)
+	}
+
+	if err = attemptRows.Err(); err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	metadata := domain.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return deliveries, metadata, nil
+}
+
+// Enqueue implements domain.WebhookRepository
+func (repository *SQLWebhookRepository) Enqueue(ctx context.Context, event *domain.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3
+		FROM webhooks
+		WHERE owner_id = $4 AND active AND (cardinality(events) = 0 OR $2 = ANY(events))
+		ON CONFLICT (webhook_id, event_id) DO NOTHING`
+
+	_, err = repository.DB.ExecContext(ctx, query, event.ID, event.Type, string(payload), event.OwnerID)
+	return err
+}
+
+// Claim implements domain.WebhookRepository
+func (repository *SQLWebhookRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookJob, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT dd.id
+			FROM webhook_deliveries dd
+			INNER JOIN webhooks ww ON ww.id = dd.webhook_id
+			WHERE dd.status = 'pending' AND dd.next_attempt_at <= NOW() AND ww.active
+			ORDER BY dd.next_attempt_at, dd.id
+			LIMIT $1
+			FOR UPDATE OF dd SKIP LOCKED)
+		RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status,
+		          d.attempts, d.next_attempt_at, d.created_at,
+		          w.id, w.owner_id, w.url, w.events, w.secret, w.active, w.failures,
+		          w.disabled_at, w.created_at, w.version`
+
+	rows, err := repository.DB.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*domain.WebhookJob{}
+
+	for rows.Next() {
+		var job domain.WebhookJob
+
+		err := rows.Scan(append(deliveryFields(&job.Delivery), webhookFields(&job.Webhook)...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, &job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Complete implements domain.WebhookRepository
+func (repository *SQLWebhookRepository) Complete(ctx context.Context, delivery *domain.WebhookDelivery, attempt *domain.WebhookAttempt, disableAfter int32) error {
+	tx, err := repository.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`
+
+	args := []any{delivery.ID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMS}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&attempt.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3
+		WHERE id = $4`
+
+	_, err = tx.ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.ID)
+	if err != nil {
+		return err
+	}
+
+	switch delivery.Status {
+	case domain.DeliverySucceeded:
+		query = `
+			UPDATE webhooks
+			SET failures = 0
+			WHERE id = $1`
+
+		_, err = tx.ExecContext(ctx, query, delivery.WebhookID)
+		if err != nil {
+			return err
+		}
+	case domain.DeliveryFailed:
+		// Disabling bumps the version, so that an edit based on the
+		// webhook as it was cannot silently enable it again.
+		query = `
+			UPDATE webhooks
+			SET failures = failures + 1,
+			    active = active AND failures + 1 < $2,
+			    disabled_at = CASE WHEN active AND failures + 1 >= $2 THEN NOW() ELSE disabled_at END,
+			    version = CASE WHEN active AND failures + 1 >= $2 THEN version + 1 ELSE version END
+			WHERE id = $1`
+
+		_, err = tx.ExecContext(ctx, query, delivery.WebhookID, disableAfter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// webhookFields returns the scan destinations for the webhook columns, in the
+// order the queries of this file select them.
+func webhookFields(webhook *domain.Webhook) []any {
+	return []any{
+		&webhook.ID,
+		&webhook.OwnerID,
+		&webhook.URL,
+		eventTypesColumn{&webhook.Events},
+		&webhook.Secret,
+		&webhook.Active,
+		&webhook.Failures,
+		&webhook.DisabledAt,
+		&webhook.CreatedAt,
+		&webhook.Version,
+	}
+}
+
+// deliveryFields returns the scan destinations for the delivery columns, in
+// the order the queries of this file select them.
+func deliveryFields(delivery *domain.WebhookDelivery) []any {
+	return []any{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.EventType,
+		(*[]byte)(&delivery.Payload),
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.CreatedAt,
+	}
+}
+
+// eventTypesColumn stores a list of event types as a text[] column, a nil
+// list as an empty array.
+type eventTypesColumn struct {
+	dest *[]domain.EventType
+}
+
+func (column eventTypesColumn) Value() (driver.Value, error) {
+	values := make(pq.StringArray, 0, len(*column.dest))
+	for _, eventType := range *column.dest {
+		values = append(values, string(eventType))
+	}
+	return values.Value()
+}
+
+func (column eventTypesColumn) Scan(src any) error {
+	var values pq.StringArray
+	if err := values.Scan(src); err != nil {
+		return err
+	}
+
+	eventTypes := make([]domain.EventType, 0, len(values))
+	for _, value := range values {
+		eventTypes = append(eventTypes, domain.EventType(value))
+	}
+	*column.dest = eventTypes
+	return nil
+}
+
+func NewWebhookRepository(conn *sql.DB) domain.WebhookRepository {
+	return &SQLWebhookRepository{conn}
+}
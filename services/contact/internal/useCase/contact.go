@@ -2,51 +2,302 @@ package useCase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
+	"advanced.microservices/pkg/phone"
+	"advanced.microservices/pkg/validator"
 	"advanced.microservices/services/contact/internal/domain"
 )
 
 type contactUsecase struct {
 	contactRepo    domain.ContactRepository
+	historyRepo    domain.HistoryRepository
+	phones         *phone.Parser
 	contextTimeout time.Duration
+	feed           *changeFeed
 }
 
 // Create implements domain.ContactUseCase
-func (uc *contactUsecase) Create(contact *domain.Contact) error {
-	ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+func (uc *contactUsecase) Create(ctx context.Context, contact *domain.Contact) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	return uc.contactRepo.Create(contact, ctx)
+	err := uc.contactRepo.Create(ctx, contact)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	uc.feed.publish(domain.ContactChange{Type: domain.ChangeCreated, Contact: *contact})
+	return nil
+}
+
+// CreateMany implements domain.ContactUseCase
+func (uc *contactUsecase) CreateMany(ctx context.Context, contacts []*domain.Contact) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	err := uc.contactRepo.CreateMany(ctx, contacts)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	for _, contact := range contacts {
+		uc.feed.publish(domain.ContactChange{Type: domain.ChangeCreated, Contact: *contact})
+	}
+	return nil
 }
 
 // Delete implements domain.ContactUseCase
-func (uc *contactUsecase) Delete(id int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+func (uc *contactUsecase) Delete(ctx context.Context, id int64, ownerID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	return uc.contactRepo.Delete(id, ctx)
+	err := uc.contactRepo.Delete(ctx, id, ownerID)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	uc.feed.publish(domain.ContactChange{Type: domain.ChangeDeleted, Contact: domain.Contact{ID: id, OwnerID: ownerID}})
+	return nil
 }
 
 // GetByID implements domain.ContactUseCase
-func (uc *contactUsecase) GetByID(id int64) (*domain.Contact, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+func (uc *contactUsecase) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Contact, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	return uc.contactRepo.GetByID(id, ctx)
+	contact, err := uc.contactRepo.GetByID(ctx, id, ownerID)
+	return contact, contextError(ctx, err)
 }
 
 // Update implements domain.ContactUseCase
-func (uc *contactUsecase) Update(contact *domain.Contact) error {
-	ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+func (uc *contactUsecase) Update(ctx context.Context, contact *domain.Contact) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	err := uc.contactRepo.Update(ctx, contact)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	uc.feed.publish(domain.ContactChange{Type: domain.ChangeUpdated, Contact: *contact})
+	return nil
+}
+
+// ListGroups implements domain.ContactUseCase
+func (uc *contactUsecase) ListGroups(ctx context.Context, contactID int64, ownerID int64) ([]*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	groups, err := uc.contactRepo.ListGroups(ctx, contactID, ownerID)
+	return groups, contextError(ctx, err)
+}
+
+// List implements domain.ContactUseCase
+func (uc *contactUsecase) List(ctx context.Context, ownerID int64, fullName string, phone string, filters domain.Filters) ([]*domain.Contact, domain.Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	contacts, metadata, err := uc.contactRepo.List(ctx, ownerID, fullName, phone, filters)
+	return contacts, metadata, contextError(ctx, err)
+}
+
+// Watch implements domain.ContactUseCase
+func (uc *contactUsecase) Watch(ctx context.Context) <-chan domain.ContactChange {
+	return uc.feed.subscribe(ctx)
+}
+
+// Export implements domain.ContactUseCase. The returned cursor keeps its
+// context alive until it is closed.
+func (uc *contactUsecase) Export(ctx context.Context, ownerID int64, groupID int64) (domain.Cursor[domain.Contact], error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	cursor, err := uc.contactRepo.Export(ctx, ownerID, groupID)
+	if err != nil {
+		cancel()
+		return nil, contextError(ctx, err)
+	}
+
+	return &cancelCursor[domain.Contact]{Cursor: cursor, ctx: ctx, cancel: cancel}, nil
+}
+
+// ListDeleted implements domain.ContactUseCase
+func (uc *contactUsecase) ListDeleted(ctx context.Context, ownerID int64, filters domain.Filters) ([]*domain.Contact, domain.Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	contacts, metadata, err := uc.contactRepo.ListDeleted(ctx, ownerID, filters)
+	return contacts, metadata, contextError(ctx, err)
+}
+
+// Restore implements domain.ContactUseCase. Watchers see a restored contact
+// as created again.
+func (uc *contactUsecase) Restore(ctx context.Context, id int64, ownerID int64) (*domain.Contact, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	contact, err := uc.contactRepo.Restore(ctx, id, ownerID)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	uc.feed.publish(domain.ContactChange{Type: domain.ChangeCreated, Contact: *contact})
+	return contact, nil
+}
+
+// PurgeDeleted implements domain.ContactUseCase. It permanently removes the
+// contacts of every owner that have been in the trash for longer than
+// retention.
+func (uc *contactUsecase) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	purged, err := uc.contactRepo.Purge(ctx, time.Now().Add(-retention))
+	return purged, contextError(ctx, err)
+}
+
+// Revert implements domain.ContactUseCase. The numbers of the version are
+// normalized again and the result validated, as the version may predate
+// E.164 forms or the current rules; a *domain.ValidationError is returned if
+// it no longer passes.
+func (uc *contactUsecase) Revert(ctx context.Context, id int64, ownerID int64, version int32) (*domain.Contact, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	entry, err := uc.historyRepo.GetVersion(ctx, domain.EntityContact, id, ownerID, version)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	var previous domain.Contact
+	if err := json.Unmarshal(entry.After, &previous); err != nil {
+		return nil, err
+	}
+
+	contact, err := uc.contactRepo.GetByID(ctx, id, ownerID)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	contact.FullName = previous.FullName
+	contact.Phone = previous.Phone
+	contact.Phones = previous.Phones
+	contact.Emails = previous.Emails
+	contact.Addresses = previous.Addresses
+	contact.Company = previous.Company
+	contact.Title = previous.Title
+	contact.Birthday = previous.Birthday
+	contact.Notes = previous.Notes
+	contact.CustomFields = previous.CustomFields
+
+	domain.NormalizePhones(contact, uc.phones)
+
+	v := validator.New()
+
+	if domain.ValidateContact(v, contact); !v.Valid() {
+		return nil, &domain.ValidationError{Errors: v.Errors}
+	}
+
+	err = uc.contactRepo.Update(ctx, contact)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	uc.feed.publish(domain.ContactChange{Type: domain.ChangeUpdated, Contact: *contact})
+	return contact, nil
+}
+
+// FindDuplicates implements domain.ContactUseCase. Every live contact of the
+// owner is compared with every other, see domain.FindDuplicates.
+func (uc *contactUsecase) FindDuplicates(ctx context.Context, ownerID int64, threshold float64) ([]*domain.DuplicateCluster, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	return uc.contactRepo.Update(contact, ctx)
+	cursor, err := uc.contactRepo.Export(ctx, ownerID, 0)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	defer cursor.Close()
+
+	contacts := []*domain.Contact{}
+	for cursor.Next() {
+		contacts = append(contacts, cursor.Value())
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return domain.FindDuplicates(contacts, threshold), nil
+}
+
+// Merge implements domain.ContactUseCase. Watchers see the contact updated and
+// the duplicate deleted.
+func (uc *contactUsecase) Merge(ctx context.Context, id int64, duplicateID int64, ownerID int64) (*domain.Contact, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	contact, err := uc.contactRepo.Merge(ctx, id, duplicateID, ownerID)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	uc.feed.publish(domain.ContactChange{Type: domain.ChangeUpdated, Contact: *contact})
+	uc.feed.publish(domain.ContactChange{Type: domain.ChangeDeleted, Contact: domain.Contact{ID: duplicateID, OwnerID: ownerID}})
+	return contact, nil
+}
+
+// BackfillPhoneE164 implements domain.ContactUseCase. Contacts stored before
+// E.164 forms were kept are filled in batches, each with its own timeout.
+// Contacts updated meanwhile are skipped, as the update stored the forms. It
+// returns the number of contacts filled in.
+func (uc *contactUsecase) BackfillPhoneE164(ctx context.Context, batch int) (int64, error) {
+	var afterID, filled int64
+	for {
+		contacts, batchFilled, err := uc.backfillPhoneE164(ctx, afterID, batch)
+		filled += batchFilled
+		if err != nil || len(contacts) < batch {
+			return filled, err
+		}
+		afterID = contacts[len(contacts)-1].ID
+	}
+}
+
+func (uc *contactUsecase) backfillPhoneE164(ctx context.Context, afterID int64, batch int) ([]*domain.Contact, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	contacts, err := uc.contactRepo.ListMissingPhoneE164(ctx, afterID, batch)
+	if err != nil {
+		return nil, 0, contextError(ctx, err)
+	}
+
+	var filled int64
+	for _, contact := range contacts {
+		if !domain.FillPhoneE164(contact, uc.phones) {
+			continue
+		}
+
+		err := uc.contactRepo.SetPhoneE164(ctx, contact)
+		switch {
+		case errors.Is(err, domain.ErrEditConflict):
+		case err != nil:
+			return contacts, filled, contextError(ctx, err)
+		default:
+			filled++
+		}
+	}
+
+	return contacts, filled, nil
 }
 
-func NewContactUsecase(c domain.ContactRepository, timeout time.Duration) domain.ContactUseCase {
+func NewContactUsecase(c domain.ContactRepository, h domain.HistoryRepository, p *phone.Parser, timeout time.Duration) domain.ContactUseCase {
 	return &contactUsecase{
 		contactRepo:    c,
+		historyRepo:    h,
+		phones:         p,
 		contextTimeout: timeout,
+		feed:           newChangeFeed(),
 	}
 }
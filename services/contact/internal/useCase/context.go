@@ -0,0 +1,25 @@
+package useCase
+
+import (
+	"context"
+	"errors"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// contextError replaces err with domain.ErrCanceled or domain.ErrTimeout when
+// it was caused by ctx ending. Any other error is returned unchanged.
+func contextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return domain.ErrCanceled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return domain.ErrTimeout
+	default:
+		return err
+	}
+}
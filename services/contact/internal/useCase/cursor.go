@@ -0,0 +1,24 @@
+package useCase
+
+import (
+	"context"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// cancelCursor releases the context a cursor was opened with once the caller
+// is done streaming.
+type cancelCursor[T any] struct {
+	domain.Cursor[T]
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (cursor *cancelCursor[T]) Err() error {
+	return contextError(cursor.ctx, cursor.Cursor.Err())
+}
+
+func (cursor *cancelCursor[T]) Close() error {
+	defer cursor.cancel()
+	return cursor.Cursor.Close()
+}
@@ -0,0 +1,50 @@
+package useCase
+
+import (
+	"context"
+	"sync"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// changeFeed fans contact changes out to every active watcher. Watchers that
+// fall behind by more than the channel buffer miss changes instead of
+// blocking writers.
+type changeFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan domain.ContactChange]struct{}
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{subscribers: make(map[chan domain.ContactChange]struct{})}
+}
+
+func (feed *changeFeed) subscribe(ctx context.Context) <-chan domain.ContactChange {
+	ch := make(chan domain.ContactChange, 64)
+
+	feed.mu.Lock()
+	feed.subscribers[ch] = struct{}{}
+	feed.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		feed.mu.Lock()
+		delete(feed.subscribers, ch)
+		close(ch)
+		feed.mu.Unlock()
+	}()
+
+	return ch
+}
+
+func (feed *changeFeed) publish(change domain.ContactChange) {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	for ch := range feed.subscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
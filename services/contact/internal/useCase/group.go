@@ -13,27 +13,102 @@ type groupUsecase struct {
 }
 
 // Create implements domain.GroupUseCase
-func (uc *groupUsecase) Create(group *domain.Group) error {
-	ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+func (uc *groupUsecase) Create(ctx context.Context, group *domain.Group) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	return uc.groupRepo.Update(group, ctx)
+	return contextError(ctx, uc.groupRepo.Create(ctx, group))
 }
 
 // GetByID implements domain.GroupUseCase
-func (uc *groupUsecase) GetByID(id int64) (*domain.Group, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+func (uc *groupUsecase) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	return uc.groupRepo.GetByID(id, ctx)
+	group, err := uc.groupRepo.GetByID(ctx, id, ownerID)
+	return group, contextError(ctx, err)
 }
 
 // Update implements domain.GroupUseCase
-func (uc *groupUsecase) Update(group *domain.Group) error {
-	ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+func (uc *groupUsecase) Update(ctx context.Context, group *domain.Group) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	return uc.groupRepo.Update(group, ctx)
+	return contextError(ctx, uc.groupRepo.Update(ctx, group))
+}
+
+// Delete implements domain.GroupUseCase
+func (uc *groupUsecase) Delete(ctx context.Context, id int64, ownerID int64, policy domain.GroupDeletePolicy) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.groupRepo.Delete(ctx, id, ownerID, policy))
+}
+
+// Move implements domain.GroupUseCase
+func (uc *groupUsecase) Move(ctx context.Context, id int64, parentID *int64, ownerID int64) (*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	group, err := uc.groupRepo.Move(ctx, id, parentID, ownerID)
+	return group, contextError(ctx, err)
+}
+
+// Ancestors implements domain.GroupUseCase
+func (uc *groupUsecase) Ancestors(ctx context.Context, id int64, ownerID int64) ([]*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	groups, err := uc.groupRepo.Ancestors(ctx, id, ownerID)
+	return groups, contextError(ctx, err)
+}
+
+// Descendants implements domain.GroupUseCase
+func (uc *groupUsecase) Descendants(ctx context.Context, id int64, ownerID int64) ([]*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	groups, err := uc.groupRepo.Descendants(ctx, id, ownerID)
+	return groups, contextError(ctx, err)
+}
+
+// AddMember implements domain.GroupUseCase
+func (uc *groupUsecase) AddMember(ctx context.Context, member *domain.GroupMember, ownerID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.groupRepo.AddMember(ctx, member, ownerID))
+}
+
+// RemoveMember implements domain.GroupUseCase
+func (uc *groupUsecase) RemoveMember(ctx context.Context, groupID int64, contactID int64, ownerID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.groupRepo.RemoveMember(ctx, groupID, contactID, ownerID))
+}
+
+// ListMembers implements domain.GroupUseCase
+func (uc *groupUsecase) ListMembers(ctx context.Context, groupID int64, ownerID int64, includeSubgroups bool) ([]*domain.Contact, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	contacts, err := uc.groupRepo.ListMembers(ctx, groupID, ownerID, includeSubgroups)
+	return contacts, contextError(ctx, err)
+}
+
+// Export implements domain.GroupUseCase. The returned cursor keeps its
+// context alive until it is closed.
+func (uc *groupUsecase) Export(ctx context.Context, ownerID int64) (domain.Cursor[domain.Group], error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	cursor, err := uc.groupRepo.Export(ctx, ownerID)
+	if err != nil {
+		cancel()
+		return nil, contextError(ctx, err)
+	}
+
+	return &cancelCursor[domain.Group]{Cursor: cursor, ctx: ctx, cancel: cancel}, nil
 }
 
 func NewGroupUsecase(c domain.GroupRepository, timeout time.Duration) domain.GroupUseCase {
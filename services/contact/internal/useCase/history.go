@@ -0,0 +1,48 @@
+package useCase
+
+import (
+	"context"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type historyUsecase struct {
+	historyRepo    domain.HistoryRepository
+	contextTimeout time.Duration
+}
+
+// List implements domain.HistoryUseCase
+func (uc *historyUsecase) List(ctx context.Context, entityType domain.EntityType, entityID int64, ownerID int64, filters domain.Filters) ([]*domain.HistoryEntry, domain.Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	entries, metadata, err := uc.historyRepo.List(ctx, entityType, entityID, ownerID, filters)
+	return entries, metadata, contextError(ctx, err)
+}
+
+// Diff implements domain.HistoryUseCase. It compares the snapshots of the
+// record as it was right after each of the two versions.
+func (uc *historyUsecase) Diff(ctx context.Context, entityType domain.EntityType, entityID int64, ownerID int64, from int32, to int32) ([]domain.FieldChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	fromEntry, err := uc.historyRepo.GetVersion(ctx, entityType, entityID, ownerID, from)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	toEntry, err := uc.historyRepo.GetVersion(ctx, entityType, entityID, ownerID, to)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return domain.DiffSnapshots(fromEntry.After, toEntry.After)
+}
+
+func NewHistoryUsecase(h domain.HistoryRepository, timeout time.Duration) domain.HistoryUseCase {
+	return &historyUsecase{
+		historyRepo:    h,
+		contextTimeout: timeout,
+	}
+}
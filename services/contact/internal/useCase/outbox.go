@@ -0,0 +1,36 @@
+package useCase
+
+import (
+	"context"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type outboxUsecase struct {
+	outboxRepo     domain.OutboxRepository
+	publisher      domain.EventPublisher
+	policy         domain.OutboxPolicy
+	contextTimeout time.Duration
+}
+
+// Relay implements domain.OutboxUseCase. It publishes at most limit pending
+// events and returns how many were published.
+func (uc *outboxUsecase) Relay(ctx context.Context, limit int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	published, err := uc.outboxRepo.Dispatch(ctx, limit, uc.policy, func(event *domain.Event) error {
+		return uc.publisher.Publish(ctx, event)
+	})
+	return published, contextError(ctx, err)
+}
+
+func NewOutboxUsecase(o domain.OutboxRepository, p domain.EventPublisher, policy domain.OutboxPolicy, timeout time.Duration) domain.OutboxUseCase {
+	return &outboxUsecase{
+		outboxRepo:     o,
+		publisher:      p,
+		policy:         policy,
+		contextTimeout: timeout,
+	}
+}
@@ -0,0 +1,101 @@
+package useCase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+// authenticationTokenTTL is the lifetime of the tokens handed out by
+// CreateAuthenticationToken.
+const authenticationTokenTTL = 24 * time.Hour
+
+type userUsecase struct {
+	userRepo       domain.UserRepository
+	tokenRepo      domain.TokenRepository
+	permissionRepo domain.PermissionRepository
+	contextTimeout time.Duration
+}
+
+// Register implements domain.UserUseCase. New users are granted
+// domain.DefaultPermissions.
+func (uc *userUsecase) Register(ctx context.Context, user *domain.User) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.userRepo.Create(ctx, user, domain.DefaultPermissions))
+}
+
+// CreateAuthenticationToken implements domain.UserUseCase. An unknown email
+// and a wrong password both yield domain.ErrInvalidCredentials.
+func (uc *userUsecase) CreateAuthenticationToken(ctx context.Context, email string, password string) (*domain.Token, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrRecordNotFound):
+			return nil, domain.ErrInvalidCredentials
+		default:
+			return nil, contextError(ctx, err)
+		}
+	}
+
+	match, err := user.Password.Matches(password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	token, err := domain.GenerateToken(user.ID, authenticationTokenTTL, domain.ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.tokenRepo.Create(ctx, token)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return token, nil
+}
+
+// DeleteAuthenticationTokens implements domain.UserUseCase
+func (uc *userUsecase) DeleteAuthenticationTokens(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.tokenRepo.DeleteAllForUser(ctx, domain.ScopeAuthentication, userID))
+}
+
+// GetForToken implements domain.UserUseCase
+func (uc *userUsecase) GetForToken(ctx context.Context, scope string, tokenPlaintext string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.userRepo.GetForToken(ctx, scope, tokenPlaintext)
+	return user, contextError(ctx, err)
+}
+
+// GetPermissions implements domain.UserUseCase
+func (uc *userUsecase) GetPermissions(ctx context.Context, userID int64) (domain.Permissions, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	permissions, err := uc.permissionRepo.GetAllForUser(ctx, userID)
+	return permissions, contextError(ctx, err)
+}
+
+func NewUserUsecase(u domain.UserRepository, t domain.TokenRepository, p domain.PermissionRepository, timeout time.Duration) domain.UserUseCase {
+	return &userUsecase{
+		userRepo:       u,
+		tokenRepo:      t,
+		permissionRepo: p,
+		contextTimeout: timeout,
+	}
+}
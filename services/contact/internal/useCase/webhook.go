@@ -0,0 +1,147 @@
+package useCase
+
+import (
+	"context"
+	"time"
+
+	"advanced.microservices/services/contact/internal/domain"
+)
+
+type webhookUsecase struct {
+	webhookRepo    domain.WebhookRepository
+	sender         domain.WebhookSender
+	policy         domain.WebhookPolicy
+	contextTimeout time.Duration
+}
+
+// Create implements domain.WebhookUseCase
+func (uc *webhookUsecase) Create(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.webhookRepo.Create(ctx, webhook))
+}
+
+// GetByID implements domain.WebhookUseCase
+func (uc *webhookUsecase) GetByID(ctx context.Context, id int64, ownerID int64) (*domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	webhook, err := uc.webhookRepo.GetByID(ctx, id, ownerID)
+	return webhook, contextError(ctx, err)
+}
+
+// List implements domain.WebhookUseCase
+func (uc *webhookUsecase) List(ctx context.Context, ownerID int64) ([]*domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	webhooks, err := uc.webhookRepo.List(ctx, ownerID)
+	return webhooks, contextError(ctx, err)
+}
+
+// Update implements domain.WebhookUseCase
+func (uc *webhookUsecase) Update(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.webhookRepo.Update(ctx, webhook))
+}
+
+// Delete implements domain.WebhookUseCase
+func (uc *webhookUsecase) Delete(ctx context.Context, id int64, ownerID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.webhookRepo.Delete(ctx, id, ownerID))
+}
+
+// ListDeliveries implements domain.WebhookUseCase
+func (uc *webhookUsecase) ListDeliveries(ctx context.Context, webhookID int64, ownerID int64, filters domain.Filters) ([]*domain.WebhookDelivery, domain.Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	deliveries, metadata, err := uc.webhookRepo.ListDeliveries(ctx, webhookID, ownerID, filters)
+	return deliveries, metadata, contextError(ctx, err)
+}
+
+// Enqueue implements domain.WebhookUseCase. It is subscribed to the event bus
+// of the outbox relay.
+func (uc *webhookUsecase) Enqueue(ctx context.Context, event *domain.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	return contextError(ctx, uc.webhookRepo.Enqueue(ctx, event))
+}
+
+// Deliver implements domain.WebhookUseCase. It makes one attempt of up to
+// limit due deliveries and returns how many were attempted. When ctx is done
+// halfway, the remaining deliveries are left to be claimed again once their
+// lease expires.
+func (uc *webhookUsecase) Deliver(ctx context.Context, limit int) (int, error) {
+	claimCtx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	lease := time.Duration(limit)*uc.policy.Timeout + uc.contextTimeout
+	jobs, err := uc.webhookRepo.Claim(claimCtx, limit, lease)
+	if err != nil {
+		return 0, contextError(claimCtx, err)
+	}
+
+	for i, job := range jobs {
+		if err := uc.attempt(ctx, job); err != nil {
+			return i, err
+		}
+	}
+	return len(jobs), nil
+}
+
+func (uc *webhookUsecase) attempt(ctx context.Context, job *domain.WebhookJob) error {
+	delivery := &job.Delivery
+
+	sendCtx, cancel := context.WithTimeout(ctx, uc.policy.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	statusCode, err := uc.sender.Send(sendCtx, &job.Webhook, delivery)
+	if ctx.Err() != nil {
+		return contextError(ctx, ctx.Err())
+	}
+
+	attempt := &domain.WebhookAttempt{
+		Attempt:    delivery.Attempts + 1,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		attempt.StatusCode = &statusCode
+	}
+
+	delivery.Attempts = attempt.Attempt
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		delivery.Status = domain.DeliverySucceeded
+		delivery.NextAttemptAt = start
+	case delivery.Attempts >= uc.policy.MaxAttempts:
+		delivery.Status = domain.DeliveryFailed
+		delivery.NextAttemptAt = start
+	default:
+		delivery.NextAttemptAt = time.Now().Add(uc.policy.RetryDelay(delivery.Attempts))
+	}
+
+	completeCtx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	err = uc.webhookRepo.Complete(completeCtx, delivery, attempt, uc.policy.DisableAfter)
+	return contextError(completeCtx, err)
+}
+
+func NewWebhookUsecase(w domain.WebhookRepository, s domain.WebhookSender, policy domain.WebhookPolicy, timeout time.Duration) domain.WebhookUseCase {
+	return &webhookUsecase{
+		webhookRepo:    w,
+		sender:         s,
+		policy:         policy,
+		contextTimeout: timeout,
+	}
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS contacts (
+    id bigserial PRIMARY KEY,
+    full_name text NOT NULL,
+    phone text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
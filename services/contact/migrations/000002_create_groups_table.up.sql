@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS groups (
+    id bigserial PRIMARY KEY,
+    group_name text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id bigint NOT NULL REFERENCES groups ON DELETE CASCADE,
+    contact_id bigint NOT NULL REFERENCES contacts ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, contact_id)
+);
+
+CREATE INDEX IF NOT EXISTS group_members_contact_id_idx ON group_members (contact_id);
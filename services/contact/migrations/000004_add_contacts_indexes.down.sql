@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS contacts_full_name_idx;
+DROP INDEX IF EXISTS contacts_phone_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS contacts_full_name_idx ON contacts USING GIN (to_tsvector('simple', full_name));
+CREATE INDEX IF NOT EXISTS contacts_phone_idx ON contacts (phone text_pattern_ops);
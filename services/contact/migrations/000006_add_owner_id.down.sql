@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS groups_owner_id_idx;
+DROP INDEX IF EXISTS contacts_owner_id_idx;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE contacts DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,7 @@
+-- Rows created before accounts existed keep a NULL owner and are no longer
+-- visible through the API.
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS owner_id bigint REFERENCES users ON DELETE CASCADE;
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS owner_id bigint REFERENCES users ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS contacts_owner_id_idx ON contacts (owner_id);
+CREATE INDEX IF NOT EXISTS groups_owner_id_idx ON groups (owner_id);
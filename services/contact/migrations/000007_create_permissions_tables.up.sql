@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    id bigserial PRIMARY KEY,
+    code text UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users_permissions (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (user_id, permission_id)
+);
+
+INSERT INTO permissions (code)
+VALUES ('contacts:read'), ('contacts:write'), ('groups:admin')
+ON CONFLICT DO NOTHING;
+
+-- Users registered before permissions existed keep the access they had.
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT u.id, p.id FROM users u CROSS JOIN permissions p
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+INSERT INTO permissions (code)
+VALUES ('logs:admin')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM contacts WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS contacts_deleted_at_idx;
+ALTER TABLE contacts DROP COLUMN IF EXISTS deleted_at;
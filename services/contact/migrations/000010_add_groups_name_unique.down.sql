@@ -0,0 +1 @@
+DROP INDEX IF EXISTS groups_owner_id_group_name_key;
@@ -0,0 +1,12 @@
+-- Disambiguate existing duplicates before enforcing uniqueness: every group
+-- but the oldest of each name gets its id appended.
+UPDATE groups g
+SET group_name = g.group_name || ' (' || g.id || ')'
+WHERE EXISTS (
+    SELECT 1 FROM groups o
+    WHERE o.owner_id = g.owner_id
+    AND lower(o.group_name) = lower(g.group_name)
+    AND o.id < g.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS groups_owner_id_group_name_key ON groups (owner_id, lower(group_name));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS groups_parent_id_idx;
+ALTER TABLE groups DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,3 @@
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS parent_id bigint REFERENCES groups ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS groups_parent_id_idx ON groups (parent_id);
@@ -0,0 +1,9 @@
+ALTER TABLE contacts
+    DROP COLUMN IF EXISTS phones,
+    DROP COLUMN IF EXISTS emails,
+    DROP COLUMN IF EXISTS addresses,
+    DROP COLUMN IF EXISTS company,
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS birthday,
+    DROP COLUMN IF EXISTS notes,
+    DROP COLUMN IF EXISTS custom_fields;
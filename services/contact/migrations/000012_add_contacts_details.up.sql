@@ -0,0 +1,9 @@
+ALTER TABLE contacts
+    ADD COLUMN IF NOT EXISTS phones jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS emails jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS addresses jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS company text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS title text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS birthday date,
+    ADD COLUMN IF NOT EXISTS notes text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS custom_fields jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS history;
+DROP FUNCTION IF EXISTS history_append_only();
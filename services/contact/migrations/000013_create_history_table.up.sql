@@ -0,0 +1,36 @@
+-- Entries deliberately have no foreign key to contacts or groups: they
+-- outlive the records they describe.
+CREATE TABLE IF NOT EXISTS history (
+    id bigserial PRIMARY KEY,
+    entity_type text NOT NULL,
+    entity_id bigint NOT NULL,
+    owner_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    version integer NOT NULL,
+    action text NOT NULL,
+    actor_id bigint REFERENCES users ON DELETE SET NULL,
+    before jsonb,
+    after jsonb,
+    changed_fields text[] NOT NULL DEFAULT '{}',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (entity_type, entity_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS history_owner_id_idx ON history (owner_id);
+
+-- The history is append-only. Rows only go away together with their owner,
+-- and actor_id is cleared when the acting user is deleted.
+CREATE OR REPLACE FUNCTION history_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.actor_id IS NULL AND OLD.actor_id IS NOT NULL
+       AND (NEW.id, NEW.entity_type, NEW.entity_id, NEW.owner_id, NEW.version, NEW.action, NEW.before, NEW.after, NEW.changed_fields, NEW.created_at)
+           IS NOT DISTINCT FROM
+           (OLD.id, OLD.entity_type, OLD.entity_id, OLD.owner_id, OLD.version, OLD.action, OLD.before, OLD.after, OLD.changed_fields, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER history_append_only
+BEFORE UPDATE ON history
+FOR EACH ROW EXECUTE FUNCTION history_append_only();
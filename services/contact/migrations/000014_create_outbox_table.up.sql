@@ -0,0 +1,12 @@
+-- Events are removed once published, so the table only holds the backlog of
+-- the relay.
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    event_type text NOT NULL,
+    owner_id bigint NOT NULL,
+    actor_id bigint,
+    payload jsonb NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text
+);
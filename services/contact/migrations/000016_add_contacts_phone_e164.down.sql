@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS contacts_phone_e164_idx;
+
+ALTER TABLE contacts DROP COLUMN IF EXISTS phone_e164;
@@ -0,0 +1,3 @@
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS phone_e164 text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS contacts_phone_e164_idx ON contacts (phone_e164 text_pattern_ops);
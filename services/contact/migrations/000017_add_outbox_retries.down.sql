@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS outbox_pending_idx;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE outbox DROP COLUMN IF EXISTS status;
@@ -0,0 +1,6 @@
+-- Events that keep failing to publish are retried with a backoff and end up
+-- failed, so that they no longer hold back the events behind them.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'pending';
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE status = 'pending';
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS holds the versioned SQL schema migrations of the contact service.
+//
+//go:embed *.sql
+var FS embed.FS